@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ArtifactsConfig routes command output that is too large for a chat message
+// to S3 instead, returning a pre-signed link.
+type ArtifactsConfig struct {
+	Bucket         string           `yaml:"bucket,omitempty"`
+	Prefix         string           `yaml:"prefix,omitempty"`
+	Region         string           `yaml:"region,omitempty"`
+	KMSKeyID       string           `yaml:"kmsKeyId,omitempty"`
+	ExpiresIn      time.Duration    `yaml:"expiresIn,omitempty"`
+	ThresholdBytes int              `yaml:"thresholdBytes,omitempty"`
+	AssumeRole     AssumeRoleConfig `yaml:"assumeRole,omitempty"`
+}
+
+const (
+	defaultArtifactThresholdBytes = 24 << 10 // 24 KiB
+	defaultArtifactExpiresIn      = time.Hour
+	artifactHeadTailLines         = 40
+)
+
+func (a ArtifactsConfig) withDefaults() ArtifactsConfig {
+	if a.ThresholdBytes <= 0 {
+		a.ThresholdBytes = defaultArtifactThresholdBytes
+	}
+	if a.ExpiresIn <= 0 {
+		a.ExpiresIn = defaultArtifactExpiresIn
+	}
+	return a
+}
+
+// artifactResult describes an output that was uploaded to S3 because it
+// exceeded the configured inline threshold.
+type artifactResult struct {
+	preview    string
+	presignURL string
+	uuid       string
+}
+
+// maybeUploadArtifact uploads outStr to S3 and returns a head/tail preview
+// plus a pre-signed URL when it exceeds cfg.ThresholdBytes. It returns
+// ok == false when cfg has no bucket configured or outStr is small enough
+// to send inline, in which case the caller should use outStr as-is.
+func maybeUploadArtifact(ctx context.Context, cfg ArtifactsConfig, outStr string) (artifactResult, bool, error) {
+	cfg = cfg.withDefaults()
+	if cfg.Bucket == "" || len(outStr) <= cfg.ThresholdBytes {
+		return artifactResult{}, false, nil
+	}
+
+	id := uuidV4()
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%s/%04d/%02d/%02d/%s.txt", strings.Trim(cfg.Prefix, "/"), now.Year(), now.Month(), now.Day(), id)
+	registerArtifact(id, cfg.Bucket, key)
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.Region))
+	if err != nil {
+		return artifactResult{}, false, fmt.Errorf("load aws config: %w", err)
+	}
+	if roleEnv, rerr := assumeRoleEnv(ctx, cfg.AssumeRole, ""); rerr == nil && len(roleEnv) > 0 {
+		// credsFromEnv keeps the artifact uploader consistent with how
+		// buildEnv injects STS credentials into the child aws process.
+		awsCfg.Credentials = credsFromEnv(roleEnv)
+	}
+
+	client := s3.NewFromConfig(awsCfg)
+	contentType := "text/plain; charset=utf-8"
+	if json.Valid([]byte(outStr)) {
+		contentType = "application/json"
+	}
+
+	put := &s3.PutObjectInput{
+		Bucket:      aws.String(cfg.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader([]byte(outStr)),
+		ContentType: aws.String(contentType),
+	}
+	if cfg.KMSKeyID != "" {
+		put.ServerSideEncryption = "aws:kms"
+		put.SSEKMSKeyId = aws.String(cfg.KMSKeyID)
+	} else {
+		put.ServerSideEncryption = "AES256"
+	}
+	if _, err := client.PutObject(ctx, put); err != nil {
+		return artifactResult{}, false, fmt.Errorf("upload artifact: %w", err)
+	}
+
+	presigner := s3.NewPresignClient(client)
+	psReq, err := presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(cfg.Bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(cfg.ExpiresIn))
+	if err != nil {
+		return artifactResult{}, false, fmt.Errorf("presign artifact url: %w", err)
+	}
+
+	return artifactResult{
+		preview:    headTail(outStr, artifactHeadTailLines),
+		presignURL: psReq.URL,
+		uuid:       id,
+	}, true, nil
+}
+
+// headTail returns the first and last n lines of s, joined with an elision
+// marker, for use as a chat-sized preview of a much larger artifact.
+func headTail(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= 2*n {
+		return s
+	}
+	head := strings.Join(lines[:n], "\n")
+	tail := strings.Join(lines[len(lines)-n:], "\n")
+	return fmt.Sprintf("%s\n... (%d lines omitted) ...\n%s", head, len(lines)-2*n, tail)
+}
+
+type artifactLocation struct {
+	bucket, key string
+}
+
+var (
+	artifactMu      sync.Mutex
+	artifactsByUUID = map[string]artifactLocation{}
+)
+
+func registerArtifact(id, bucket, key string) {
+	artifactMu.Lock()
+	artifactsByUUID[id] = artifactLocation{bucket: bucket, key: key}
+	artifactMu.Unlock()
+}
+
+// fetchArtifactPage re-downloads the artifact registered under id and
+// returns the requested 1-indexed page of pageSize lines.
+func fetchArtifactPage(ctx context.Context, cfg ArtifactsConfig, id string, page, pageSize int) (string, error) {
+	artifactMu.Lock()
+	loc, ok := artifactsByUUID[id]
+	artifactMu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("unknown artifact %q (not uploaded by this plugin instance)", id)
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.Region))
+	if err != nil {
+		return "", fmt.Errorf("load aws config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg)
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(loc.bucket), Key: aws.String(loc.key)})
+	if err != nil {
+		return "", fmt.Errorf("fetch artifact: %w", err)
+	}
+	defer out.Body.Close()
+	body, err := io.ReadAll(out.Body)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(string(body), "\n")
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * pageSize
+	if start >= len(lines) {
+		return fmt.Sprintf("(page %d is past the end of the artifact, %d lines total)", page, len(lines)), nil
+	}
+	end := start + pageSize
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return strings.Join(lines[start:end], "\n"), nil
+}
+
+func uuidV4() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
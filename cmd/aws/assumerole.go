@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/google/shlex"
+)
+
+// AssumeRoleConfig describes an STS role the executor should assume before
+// running `aws` on behalf of a chat command.
+type AssumeRoleConfig struct {
+	RoleArn              string   `yaml:"roleArn,omitempty"`
+	SessionName          string   `yaml:"sessionName,omitempty"`
+	ExternalID           string   `yaml:"externalId,omitempty"`
+	DurationSeconds      int32    `yaml:"durationSeconds,omitempty"`
+	MFASerial            string   `yaml:"mfaSerial,omitempty"`
+	SourceProfile        string   `yaml:"sourceProfile,omitempty"`
+	WebIdentityTokenFile string   `yaml:"webIdentityTokenFile,omitempty"`
+	PolicyArns           []string `yaml:"policyArns,omitempty"`
+	CaFile               string   `yaml:"caFile,omitempty"`
+}
+
+// Profile lets operators scope a distinct role and allow-list behind a name,
+// selectable per-command via `aws --as <profile> ...`.
+type Profile struct {
+	AssumeRole AssumeRoleConfig `yaml:"assumeRole,omitempty"`
+	Allowed    []string         `yaml:"allowed,omitempty"`
+}
+
+var sessionNameRE = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// sanitizeSessionName trims and filters s to match STS's session name
+// charset ([\w+=,.@-]{2,64}).
+func sanitizeSessionName(s string) string {
+	s = sessionNameRE.ReplaceAllString(s, "-")
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	if len(s) < 2 {
+		s = (s + "--")[:2]
+	}
+	return s
+}
+
+// sessionNameForUser derives the default STS session name from the chat
+// user who triggered the command, so CloudTrail shows who initiated an
+// assumed-role call instead of every invocation showing up under the same
+// generic "botkube-aws-session" name. Falls back to displayName, and to ""
+// (letting assumeRoleEnv use its generic default) when the platform gives
+// neither.
+func sessionNameForUser(mention, displayName string) string {
+	who := mention
+	if who == "" {
+		who = displayName
+	}
+	if who == "" {
+		return ""
+	}
+	return sanitizeSessionName("botkube-" + pluginName + "-" + who)
+}
+
+// extractProfileOverride pulls a leading/embedded `--as <profile>` token out
+// of cmdLine and returns the selected profile name plus the remaining
+// command line to execute.
+func extractProfileOverride(cmdLine string) (profile, rest string) {
+	tokens, err := shlex.Split(cmdLine)
+	if err != nil || len(tokens) == 0 {
+		return "", cmdLine
+	}
+	for i, t := range tokens {
+		if t == "--as" && i+1 < len(tokens) {
+			remaining := append(append([]string{}, tokens[:i]...), tokens[i+2:]...)
+			return tokens[i+1], strings.Join(remaining, " ")
+		}
+	}
+	return "", cmdLine
+}
+
+// resolveProfile returns the effective AssumeRole config and allow-list for
+// the given profile name, falling back to the top-level Config when the
+// profile is empty or unknown.
+func resolveProfile(cfg Config, profileName string) (AssumeRoleConfig, []string) {
+	if profileName == "" {
+		return cfg.AssumeRole, cfg.Allowed
+	}
+	p, ok := cfg.Profiles[profileName]
+	if !ok {
+		return cfg.AssumeRole, cfg.Allowed
+	}
+	allowed := p.Allowed
+	if len(allowed) == 0 {
+		allowed = cfg.Allowed
+	}
+	return p.AssumeRole, allowed
+}
+
+type roleCredEntry struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	expiration      time.Time
+}
+
+var (
+	roleCredMu    sync.Mutex
+	roleCredCache = map[string]roleCredEntry{}
+)
+
+func roleCacheKey(roleArn, sessionName string) string {
+	return roleArn + "|" + sessionName
+}
+
+// assumeRoleEnv resolves AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/AWS_SESSION_TOKEN
+// env entries for ar, calling sts:AssumeRole (or AssumeRoleWithWebIdentity when
+// a web identity token file is configured) and caching the result until 5
+// minutes before expiry. Returns nil, nil when ar has no role configured, in
+// which case the child process falls back to ambient credentials. When ar
+// has no SessionName configured, userHint (see sessionNameForUser) is used
+// as the default session name so CloudTrail can attribute the assumed-role
+// call to the chat user who triggered it; pass "" where no user is known
+// (e.g. the __eks-token re-exec path, which runs outside any chat context).
+func assumeRoleEnv(ctx context.Context, ar AssumeRoleConfig, userHint string) ([]string, error) {
+	if ar.RoleArn == "" {
+		return nil, nil
+	}
+	sessionName := ar.SessionName
+	if sessionName == "" {
+		sessionName = userHint
+	}
+	if sessionName == "" {
+		sessionName = sanitizeSessionName("botkube-" + pluginName + "-session")
+	}
+	key := roleCacheKey(ar.RoleArn, sessionName)
+
+	roleCredMu.Lock()
+	if entry, ok := roleCredCache[key]; ok && time.Now().Add(5*time.Minute).Before(entry.expiration) {
+		roleCredMu.Unlock()
+		return credEnv(entry), nil
+	}
+	roleCredMu.Unlock()
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	client := sts.NewFromConfig(awsCfg)
+
+	entry, err := doAssumeRole(ctx, client, ar, sessionName)
+	if err != nil {
+		return nil, err
+	}
+
+	roleCredMu.Lock()
+	roleCredCache[key] = entry
+	roleCredMu.Unlock()
+	return credEnv(entry), nil
+}
+
+func doAssumeRole(ctx context.Context, client *sts.Client, ar AssumeRoleConfig, sessionName string) (roleCredEntry, error) {
+	var duration *int32
+	if ar.DurationSeconds > 0 {
+		duration = aws.Int32(ar.DurationSeconds)
+	}
+
+	if ar.WebIdentityTokenFile != "" {
+		token, err := os.ReadFile(ar.WebIdentityTokenFile)
+		if err != nil {
+			return roleCredEntry{}, fmt.Errorf("read web identity token: %w", err)
+		}
+		out, err := client.AssumeRoleWithWebIdentity(ctx, &sts.AssumeRoleWithWebIdentityInput{
+			RoleArn:          aws.String(ar.RoleArn),
+			RoleSessionName:  aws.String(sessionName),
+			WebIdentityToken: aws.String(string(token)),
+			DurationSeconds:  duration,
+			PolicyArns:       policyArnsOf(ar.PolicyArns),
+		})
+		if err != nil {
+			return roleCredEntry{}, fmt.Errorf("assume-role-with-web-identity: %w", err)
+		}
+		return credEntryOf(out.Credentials), nil
+	}
+
+	in := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(ar.RoleArn),
+		RoleSessionName: aws.String(sessionName),
+		DurationSeconds: duration,
+		PolicyArns:      policyArnsOf(ar.PolicyArns),
+	}
+	if ar.ExternalID != "" {
+		in.ExternalId = aws.String(ar.ExternalID)
+	}
+	if ar.MFASerial != "" {
+		in.SerialNumber = aws.String(ar.MFASerial)
+	}
+	out, err := client.AssumeRole(ctx, in)
+	if err != nil {
+		return roleCredEntry{}, fmt.Errorf("assume-role: %w", err)
+	}
+	return credEntryOf(out.Credentials), nil
+}
+
+func policyArnsOf(arns []string) []types.PolicyDescriptorType {
+	if len(arns) == 0 {
+		return nil
+	}
+	out := make([]types.PolicyDescriptorType, 0, len(arns))
+	for _, a := range arns {
+		out = append(out, types.PolicyDescriptorType{Arn: aws.String(a)})
+	}
+	return out
+}
+
+func credEntryOf(c *types.Credentials) roleCredEntry {
+	if c == nil {
+		return roleCredEntry{}
+	}
+	return roleCredEntry{
+		accessKeyID:     aws.ToString(c.AccessKeyId),
+		secretAccessKey: aws.ToString(c.SecretAccessKey),
+		sessionToken:    aws.ToString(c.SessionToken),
+		expiration:      aws.ToTime(c.Expiration),
+	}
+}
+
+func credEnv(e roleCredEntry) []string {
+	return []string{
+		"AWS_ACCESS_KEY_ID=" + e.accessKeyID,
+		"AWS_SECRET_ACCESS_KEY=" + e.secretAccessKey,
+		"AWS_SESSION_TOKEN=" + e.sessionToken,
+	}
+}
+
+// credsFromEnv turns the AWS_*-prefixed env entries produced by credEnv back
+// into a static aws-sdk-go-v2 credentials provider, so SDK calls made by the
+// plugin itself (e.g. the S3 artifact sink) can reuse the same assumed-role
+// session as the child `aws` process.
+func credsFromEnv(env []string) aws.CredentialsProvider {
+	var accessKeyID, secretAccessKey, sessionToken string
+	for _, e := range env {
+		switch {
+		case strings.HasPrefix(e, "AWS_ACCESS_KEY_ID="):
+			accessKeyID = strings.TrimPrefix(e, "AWS_ACCESS_KEY_ID=")
+		case strings.HasPrefix(e, "AWS_SECRET_ACCESS_KEY="):
+			secretAccessKey = strings.TrimPrefix(e, "AWS_SECRET_ACCESS_KEY=")
+		case strings.HasPrefix(e, "AWS_SESSION_TOKEN="):
+			sessionToken = strings.TrimPrefix(e, "AWS_SESSION_TOKEN=")
+		}
+	}
+	return aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			SessionToken:    sessionToken,
+		}, nil
+	})
+}
@@ -1,67 +1,156 @@
 package main
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/hskoon0722/botkube-awscli/pkg/extract"
+	"golang.org/x/crypto/openpgp" //nolint:staticcheck // no maintained replacement for detached-sig verify
 )
 
-// prepareAws returns AWS CLI binary and library dirs, ensuring they are ready.
-func prepareAws(ctx context.Context) (awsBin, glibcDir, distDir string, _ error) {
-	return ensureFromBundle(ctx)
+// prepareAws returns AWS CLI binary and library dirs, ensuring they are
+// ready. When cfg resolves to InstallModeSystem, awsBin comes from the
+// host's PATH and glibcDir/distDir are empty, so resolveLoaderPath/
+// buildLDPath/runAWS already skip the ld-linux loader trick with no further
+// changes needed.
+func prepareAws(ctx context.Context, cfg Config) (awsBin, glibcDir, distDir string, _ error) {
+	if resolveInstallMode(cfg) == InstallModeSystem {
+		bin, err := ensureFromSystem(ctx, cfg)
+		return bin, "", "", err
+	}
+	return ensureFromBundle(ctx, cfg)
 }
 
-// ensureFromBundle prepares AWS CLI from the prebuilt tar.gz bundle.
-func ensureFromBundle(ctx context.Context) (awsBin, glibcDir, distDir string, _ error) {
+// ensureFromBundle prepares AWS CLI from the prebuilt tar.gz bundle, verifying
+// its integrity against a pinned SHA-256 digest (and, optionally, a detached
+// signature) before it is ever extracted. Downloaded bundles land in a
+// content-addressable cache under depsDir() (see bundlecache.go) keyed by
+// that digest, so a hot-restart loop or several plugin instances sharing a
+// base-dir skip both the download and the extraction once a digest has been
+// fetched once.
+func ensureFromBundle(ctx context.Context, cfg Config) (awsBin, glibcDir, distDir string, err error) {
+	defer func() { recordBundleError(err) }()
+
 	depsRoot, err := depsDir()
 	if err != nil {
 		return "", "", "", err
 	}
-	bundleRoot := filepath.Join(depsRoot, "bundle")
-	distDir = filepath.Join(bundleRoot, "awscli", "dist")
-	glibcDir = filepath.Join(bundleRoot, "glibc")
-	awsBin = filepath.Join(distDir, "aws")
-
-	// Already prepared?
-	if isExecutable(awsBin) {
-		if _, err := os.Stat(glibcDir); err == nil {
-			return awsBin, glibcDir, distDir, nil
-		}
-	}
-
-	if err := os.MkdirAll(bundleRoot, 0o755); err != nil {
-		return "", "", "", err
-	}
 
 	arch := runtime.GOARCH
-	url := os.Getenv("AWSCLI_TARBALL_URL_" + strings.ToUpper(arch))
+	archUpper := strings.ToUpper(arch)
+	url := os.Getenv("AWSCLI_TARBALL_URL_" + archUpper)
 	if url == "" {
 		url = defaultBundleURL[arch]
 	}
 	if url == "" {
 		return "", "", "", fmt.Errorf(
 			"no bundle url configured for arch %q (set AWSCLI_TARBALL_URL_%s)",
-			arch, strings.ToUpper(arch),
+			arch, archUpper,
 		)
 	}
 
+	wantDigest := strings.ToLower(bundleDigestFor(cfg, arch))
+	if cfg.pinOverride != "" {
+		pinned, perr := resolvePin(cfg.pinOverride)
+		if perr != nil {
+			return "", "", "", perr
+		}
+		wantDigest = pinned
+	}
+	sigURL := bundleSigURLFor(cfg, arch)
+
+	// A digest we already know about (pinned, or remembered from a prior
+	// fetch of this same URL) may already have a usable extraction on
+	// disk; check before touching the network at all.
+	sha := wantDigest
+	prevIdx, hadPrev := readURLIndex(depsRoot)[url]
+	if sha == "" && hadPrev {
+		sha = prevIdx.SHA256
+	}
+	if sha != "" {
+		if bin, gd, dd, ok := cachedBundlePaths(depsRoot, sha); ok {
+			_ = setCurrentCacheDir(depsRoot, cacheEntryDir(depsRoot, sha))
+			return bin, gd, dd, nil
+		}
+	}
+
+	fetcher, ferr := fetcherFor(url)
+	if ferr != nil {
+		return "", "", "", ferr
+	}
 	tmp := filepath.Join(os.TempDir(), fmt.Sprintf("awsbundle-%d.tar.gz", time.Now().UnixNano()))
-	if err := httpGetToFile(ctx, url, tmp); err != nil {
+
+	var idx urlIndexEntry
+	if hf, ok := fetcher.(httpFetcher); ok {
+		var prevPtr *urlIndexEntry
+		if hadPrev {
+			prevPtr = &prevIdx
+		}
+		idx, _, err = hf.FetchConditional(ctx, url, tmp, prevPtr)
+	} else {
+		idx.SHA256, err = fetcher.Fetch(ctx, url, tmp)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
 		return "", "", "", fmt.Errorf("download bundle: %w", err)
 	}
 	defer func() { _ = os.Remove(tmp) }()
 
-	if err := untarGzSafe(tmp, bundleRoot); err != nil {
-		return "", "", "", fmt.Errorf("extract bundle: %w", err)
+	gotDigest := idx.SHA256
+	if wantDigest != "" {
+		if subtle.ConstantTimeCompare([]byte(gotDigest), []byte(wantDigest)) != 1 {
+			return "", "", "", fmt.Errorf("bundle checksum mismatch: got %s want %s", gotDigest, wantDigest)
+		}
+	}
+	if sigURL != "" {
+		if err := verifyBundleSignature(ctx, tmp, sigURL, cfg.BundleVerify.PubKey); err != nil {
+			return "", "", "", fmt.Errorf("bundle signature verification failed: %w", err)
+		}
 	}
 
+	entryDir := cacheEntryDir(depsRoot, gotDigest)
+	extractedDir := cacheExtractedDir(entryDir)
+	distDir = filepath.Join(extractedDir, "awscli", "dist")
+	glibcDir = filepath.Join(extractedDir, "glibc")
+	awsBin = filepath.Join(distDir, "aws")
+
+	if meta, merr := readCacheMeta(entryDir); merr == nil && manifestIntact(extractedDir, meta) {
+		writeURLIndexEntry(depsRoot, url, idx)
+		_ = setCurrentCacheDir(depsRoot, entryDir)
+		return awsBin, glibcDir, distDir, nil
+	}
+
+	if err := os.MkdirAll(entryDir, 0o755); err != nil {
+		return "", "", "", err
+	}
+	if err := copyFile(tmp, cacheTarballPath(entryDir)); err != nil {
+		return "", "", "", err
+	}
+
+	files, uerr := untarGzSafe(cacheTarballPath(entryDir), extractedDir)
+	if uerr != nil {
+		return "", "", "", fmt.Errorf("extract bundle: %w", uerr)
+	}
+	writeVerifiedMarker(extractedDir, gotDigest, sigURL != "")
+	if werr := writeCacheMeta(entryDir, bundleMeta{
+		URL: url, ETag: idx.ETag, LastModified: idx.LastModified,
+		SHA256: gotDigest, ExtractedAt: time.Now(), Files: files,
+	}); werr != nil {
+		return "", "", "", werr
+	}
+	writeURLIndexEntry(depsRoot, url, idx)
+	_ = setCurrentCacheDir(depsRoot, entryDir)
+
 	_ = os.Chmod(awsBin, 0o755)
 	for _, ld := range []string{
 		filepath.Join(glibcDir, "ld-linux-x86-64.so.2"),
@@ -74,72 +163,205 @@ func ensureFromBundle(ctx context.Context) (awsBin, glibcDir, distDir string, _
 	return awsBin, glibcDir, distDir, nil
 }
 
-// untarGzSafe extracts tar.gz safely with size/path checks.
-func untarGzSafe(src, dst string) error {
-	f, err := os.Open(src)
+// cachedBundlePaths reports whether depsRoot already has a complete,
+// verified extraction for sha, returning its aws/glibc/dist paths if so.
+func cachedBundlePaths(depsRoot, sha string) (awsBin, glibcDir, distDir string, ok bool) {
+	entryDir := cacheEntryDir(depsRoot, sha)
+	extractedDir := cacheExtractedDir(entryDir)
+	distDir = filepath.Join(extractedDir, "awscli", "dist")
+	glibcDir = filepath.Join(extractedDir, "glibc")
+	awsBin = filepath.Join(distDir, "aws")
+	meta, err := readCacheMeta(entryDir)
+	if err != nil || !manifestIntact(extractedDir, meta) {
+		return "", "", "", false
+	}
+	return awsBin, glibcDir, distDir, true
+}
+
+// bundleDigestFor returns the pinned SHA-256 digest for arch, preferring an
+// explicit env var override over the Config value.
+func bundleDigestFor(cfg Config, arch string) string {
+	if v := os.Getenv("AWSCLI_TARBALL_SHA256_" + strings.ToUpper(arch)); v != "" {
+		return v
+	}
+	switch arch {
+	case "amd64":
+		return cfg.BundleVerify.SHA256Amd64
+	case "arm64":
+		return cfg.BundleVerify.SHA256Arm64
+	default:
+		return ""
+	}
+}
+
+// bundleSigURLFor returns the detached signature URL for arch, if any.
+func bundleSigURLFor(cfg Config, arch string) string {
+	if v := os.Getenv("AWSCLI_TARBALL_SIG_URL_" + strings.ToUpper(arch)); v != "" {
+		return v
+	}
+	switch arch {
+	case "amd64":
+		return cfg.BundleVerify.SigURLAmd64
+	case "arm64":
+		return cfg.BundleVerify.SigURLArm64
+	default:
+		return ""
+	}
+}
+
+// httpGetToFileVerified downloads url to dst, hashing the stream as it is
+// written so the digest is available without a second pass over the file.
+func httpGetToFileVerified(ctx context.Context, url, dst string) (sha256Hex string, _ error) {
+	hasher := sha256.New()
+	if err := httpGetToWriter(ctx, url, dst, hasher); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// verifyBundleSignature fetches the detached PGP signature at sigURL and
+// checks it against bundlePath using the configured armored public key.
+func verifyBundleSignature(ctx context.Context, bundlePath, sigURL, pubKeyArmored string) error {
+	if pubKeyArmored == "" {
+		return fmt.Errorf("AWSCLI_TARBALL_SIG_URL set but no bundleVerify.pubKey configured")
+	}
+	sigTmp := bundlePath + ".sig"
+	if err := httpGetToFile(ctx, sigURL, sigTmp); err != nil {
+		return fmt.Errorf("download signature: %w", err)
+	}
+	defer func() { _ = os.Remove(sigTmp) }()
+
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(pubKeyArmored))
+	if err != nil {
+		return fmt.Errorf("parse public key: %w", err)
+	}
+	bundleFile, err := os.Open(bundlePath)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	gz, err := gzip.NewReader(f)
+	defer bundleFile.Close()
+	sigFile, err := os.Open(sigTmp)
 	if err != nil {
 		return err
 	}
-	defer gz.Close()
+	defer sigFile.Close()
 
-	tr := tar.NewReader(gz)
-	var extracted int64
+	if _, err := openpgp.CheckDetachedSignature(keyring, bundleFile, sigFile); err != nil {
+		return fmt.Errorf("signature check: %w", err)
+	}
+	return nil
+}
 
-	for {
-		h, err := tr.Next()
-		if err == io.EOF {
-			return nil
-		}
-		if err != nil {
-			return err
-		}
+// bundleVerification is the content of bundleRoot/.verified, recording what
+// was checked for the bundle currently extracted into that root so `helper
+// diag` can report it without re-downloading or re-hashing anything.
+type bundleVerification struct {
+	SHA256      string    `json:"sha256"`
+	SignatureOK bool      `json:"signatureOk"`
+	VerifiedAt  time.Time `json:"verifiedAt"`
+}
 
-		switch h.Typeflag {
-		case tar.TypeDir, tar.TypeReg:
-		default:
-			continue
-		}
+func writeVerifiedMarker(bundleRoot, digest string, signatureChecked bool) {
+	v := bundleVerification{SHA256: digest, SignatureOK: signatureChecked, VerifiedAt: time.Now()}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(bundleRoot, ".verified"), b, 0o644)
+}
 
-		target, err := safeJoin(dst, h.Name)
-		if err != nil {
-			return err
-		}
+// readBundleVerification loads bundleRoot/.verified, if present, for
+// diagnostics output. The marker is written after every successful
+// extraction, pinned or not, so a missing marker means no extraction has
+// completed into bundleRoot (e.g. the bundle predates this feature).
+func readBundleVerification(bundleRoot string) (*bundleVerification, error) {
+	b, err := os.ReadFile(filepath.Join(bundleRoot, ".verified"))
+	if err != nil {
+		return nil, err
+	}
+	var v bundleVerification
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
 
-		if h.Typeflag == tar.TypeDir {
-			if err := os.MkdirAll(target, 0o755); err != nil {
-				return err
-			}
-			continue
-		}
+const maxRecentBundleErrors = 10
 
-		if h.Size < 0 || h.Size > maxEntryBytes {
-			return fmt.Errorf("tar entry too large: %d bytes", h.Size)
-		}
-		if extracted+h.Size > maxExtractBytes {
-			return fmt.Errorf("tar total size exceeds limit")
-		}
-		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
-			return err
-		}
+var (
+	bundleErrMu sync.Mutex
+	bundleErrs  []string
+)
 
-		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
-		if err != nil {
-			return err
-		}
-		_, cpErr := io.CopyN(out, tr, h.Size)
-		clErr := out.Close()
-		if cpErr != nil && cpErr != io.EOF {
-			return cpErr
-		}
-		if clErr != nil {
-			return clErr
+// recordBundleError keeps the last few ensureFromBundle failures around so
+// `helper diag` can show them without anyone having to reproduce the
+// failure live.
+func recordBundleError(err error) {
+	if err == nil {
+		return
+	}
+	bundleErrMu.Lock()
+	defer bundleErrMu.Unlock()
+	bundleErrs = append(bundleErrs, time.Now().Format(time.RFC3339)+": "+err.Error())
+	if len(bundleErrs) > maxRecentBundleErrors {
+		bundleErrs = bundleErrs[len(bundleErrs)-maxRecentBundleErrors:]
+	}
+}
+
+func recentBundleErrors() []string {
+	bundleErrMu.Lock()
+	defer bundleErrMu.Unlock()
+	out := make([]string, len(bundleErrs))
+	copy(out, bundleErrs)
+	return out
+}
+
+// bundleDiagSection reports the bundle verification state, the "bundle"
+// part of the broader `helper diag` output assembled in diag.go.
+func bundleDiagSection(cfg Config) string {
+	depsRoot, err := depsDir()
+	if err != nil {
+		return "diag: " + err.Error()
+	}
+	arch := runtime.GOARCH
+	sha := strings.ToLower(bundleDigestFor(cfg, arch))
+	if sha == "" {
+		if link, lerr := os.Readlink(currentLinkPath(depsRoot)); lerr == nil {
+			sha = filepath.Base(link)
 		}
-		extracted += h.Size
 	}
+	entryDir := cacheEntryDir(depsRoot, sha)
+	extractedDir := cacheExtractedDir(entryDir)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "arch: %s\n", arch)
+	fmt.Fprintf(&b, "cache entry: %s\n", entryDir)
+	v, err := readBundleVerification(extractedDir)
+	if err != nil {
+		fmt.Fprintf(&b, "bundle verification: not recorded (%v)\n", err)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "bundle sha256: %s\n", v.SHA256)
+	fmt.Fprintf(&b, "signature checked: %t\n", v.SignatureOK)
+	fmt.Fprintf(&b, "verified at: %s\n", v.VerifiedAt.Format(time.RFC3339))
+	return b.String()
+}
+
+// untarGzSafe extracts tar.gz safely, delegating the actual size-limit and
+// path-safety enforcement to pkg/extract, and returns a manifest of the
+// regular files it wrote so a later run can confirm the extraction is
+// still intact (see manifestIntact) without re-reading src.
+func untarGzSafe(src, dst string) ([]bundleManifestEntry, error) {
+	files, err := extract.TarGz(src, dst, extract.Options{
+		MaxEntryBytes:   maxEntryBytes,
+		MaxArchiveBytes: maxExtractBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	manifest := make([]bundleManifestEntry, 0, len(files))
+	for _, f := range files {
+		manifest = append(manifest, bundleManifestEntry{Path: f.Path, Executable: f.Executable})
+	}
+	return manifest, nil
 }
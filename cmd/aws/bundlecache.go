@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Content-addressable bundle cache layout, rooted at depsDir():
+//
+//	cache/<sha256>/aws.tar.gz     the verified, downloaded tarball
+//	cache/<sha256>/extracted/     that tarball's extraction
+//	cache/<sha256>/.meta.json     bundleMeta for this entry
+//	cache/url-index.json          url -> last-seen sha/etag/lastModified
+//	current                       symlink -> cache/<sha256> actually in use
+//
+// This lets a hot-restart loop, or several plugin instances sharing one
+// base-dir, skip both the download and the extraction once a digest has
+// been fetched once.
+
+// bundleManifestEntry is one file untarGzSafe wrote, recorded so a later
+// ensureFromBundle call can confirm the extraction is still intact without
+// re-reading the tarball.
+type bundleManifestEntry struct {
+	Path       string `json:"path"`
+	Executable bool   `json:"executable"`
+}
+
+// bundleMeta is the content of a cache entry's .meta.json.
+type bundleMeta struct {
+	URL          string                `json:"url"`
+	ETag         string                `json:"etag,omitempty"`
+	LastModified string                `json:"lastModified,omitempty"`
+	Size         int64                 `json:"size"`
+	SHA256       string                `json:"sha256"`
+	ExtractedAt  time.Time             `json:"extractedAt"`
+	Files        []bundleManifestEntry `json:"files"`
+}
+
+func cacheRootDir(depsRoot string) string      { return filepath.Join(depsRoot, "cache") }
+func cacheEntryDir(depsRoot, sha string) string { return filepath.Join(cacheRootDir(depsRoot), sha) }
+func cacheTarballPath(entryDir string) string   { return filepath.Join(entryDir, "aws.tar.gz") }
+func cacheExtractedDir(entryDir string) string  { return filepath.Join(entryDir, "extracted") }
+func cacheMetaPath(entryDir string) string      { return filepath.Join(entryDir, ".meta.json") }
+func currentLinkPath(depsRoot string) string    { return filepath.Join(depsRoot, "current") }
+
+func writeCacheMeta(entryDir string, m bundleMeta) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cacheMetaPath(entryDir), b, 0o644)
+}
+
+func readCacheMeta(entryDir string) (*bundleMeta, error) {
+	b, err := os.ReadFile(cacheMetaPath(entryDir))
+	if err != nil {
+		return nil, err
+	}
+	var m bundleMeta
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// manifestIntact reports whether every file m.Files recorded is still
+// present (and, for entries extracted with an executable bit set, still
+// executable per isExecutable), so ensureFromBundle can skip extraction
+// entirely when the cache already has a usable copy.
+func manifestIntact(extractedDir string, m *bundleMeta) bool {
+	if m == nil || len(m.Files) == 0 {
+		return false
+	}
+	for _, f := range m.Files {
+		p := filepath.Join(extractedDir, f.Path)
+		if f.Executable {
+			if !isExecutable(p) {
+				return false
+			}
+			continue
+		}
+		if _, err := os.Stat(p); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// setCurrentCacheDir repoints depsRoot/current at entryDir.
+func setCurrentCacheDir(depsRoot, entryDir string) error {
+	link := currentLinkPath(depsRoot)
+	_ = os.Remove(link)
+	return os.Symlink(entryDir, link)
+}
+
+// urlIndexEntry is what cache/url-index.json remembers about the last
+// bundle fetched for a given URL, so a subsequent run can send conditional
+// headers (and potentially skip the download outright) even when no
+// digest was pinned in config.
+type urlIndexEntry struct {
+	SHA256       string `json:"sha256"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+func urlIndexPath(depsRoot string) string { return filepath.Join(cacheRootDir(depsRoot), "url-index.json") }
+
+func readURLIndex(depsRoot string) map[string]urlIndexEntry {
+	out := map[string]urlIndexEntry{}
+	b, err := os.ReadFile(urlIndexPath(depsRoot))
+	if err != nil {
+		return out
+	}
+	_ = json.Unmarshal(b, &out)
+	return out
+}
+
+func writeURLIndexEntry(depsRoot, url string, e urlIndexEntry) {
+	if err := os.MkdirAll(cacheRootDir(depsRoot), 0o755); err != nil {
+		return
+	}
+	idx := readURLIndex(depsRoot)
+	idx[url] = e
+	b, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(urlIndexPath(depsRoot), b, 0o644)
+}
+
+var sha256RE = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// resolvePin validates a --pin value. This plugin only ever tracks a
+// single release URL per arch (see defaultBundleURL), not a table of
+// versions to resolve, so only a literal sha256 digest is supported today;
+// a version string is rejected with an explanation rather than silently
+// ignored.
+func resolvePin(pin string) (string, error) {
+	pin = strings.ToLower(strings.TrimSpace(pin))
+	if pin == "" {
+		return "", nil
+	}
+	if !sha256RE.MatchString(pin) {
+		return "", fmt.Errorf("--pin %q is not a sha256 digest; version-based pins aren't supported (this plugin tracks one bundle URL per arch, not multiple versions)", pin)
+	}
+	return pin, nil
+}
+
+// extractPinOverride pulls a leading/embedded `--pin <sha256>` token out of
+// cmdLine, mirroring extractProfileOverride's `--as` handling.
+func extractPinOverride(cmdLine string) (pin, rest string) {
+	fields := strings.Fields(cmdLine)
+	for i, f := range fields {
+		if f == "--pin" && i+1 < len(fields) {
+			remaining := append(append([]string{}, fields[:i]...), fields[i+2:]...)
+			return fields[i+1], strings.Join(remaining, " ")
+		}
+	}
+	return "", cmdLine
+}
+
+// gcResult summarizes a `aws gc` run.
+type gcResult struct {
+	Pruned      []string
+	FreedBytes  int64
+	KeptCurrent string
+}
+
+// runBundleGC removes cache entries older than ttl, except whichever one
+// depsRoot/current points at. "Older" is judged by the cache entry's
+// .meta.json ExtractedAt, falling back to the newest mtime of anything under
+// the entry directory when no meta is present.
+func runBundleGC(depsRoot string, ttl time.Duration) (gcResult, error) {
+	var res gcResult
+
+	current, _ := os.Readlink(currentLinkPath(depsRoot))
+	res.KeptCurrent = filepath.Base(current)
+
+	entries, err := os.ReadDir(cacheRootDir(depsRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return res, nil
+		}
+		return res, err
+	}
+
+	cutoff := time.Now().Add(-ttl)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue // skip url-index.json etc.
+		}
+		entryDir := filepath.Join(cacheRootDir(depsRoot), e.Name())
+		if entryDir == current {
+			continue
+		}
+		modTime, size := entryStat(entryDir)
+		if meta, merr := readCacheMeta(entryDir); merr == nil {
+			modTime = meta.ExtractedAt
+		}
+		if modTime.After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(entryDir); err != nil {
+			return res, fmt.Errorf("prune %s: %w", entryDir, err)
+		}
+		res.Pruned = append(res.Pruned, e.Name())
+		res.FreedBytes += size
+	}
+	return res, nil
+}
+
+// entryStat returns the newest modification time of anything under a cache
+// entry directory and its total size on disk, used as a GC fallback when
+// .meta.json is missing.
+func entryStat(dir string) (time.Time, int64) {
+	var total int64
+	var newest time.Time
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info == nil {
+			return nil //nolint:nilerr // best-effort size/mtime accounting
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	return newest, total
+}
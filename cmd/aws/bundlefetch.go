@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Fetcher retrieves ref into dst, hashing the bytes as they are written so
+// callers get a verifiable digest without a second pass over the file.
+type Fetcher interface {
+	Fetch(ctx context.Context, ref, dst string) (sha256Hex string, err error)
+}
+
+// fetcherFor selects a Fetcher by ref's URL scheme, mirroring how
+// setup-envtest splits its remote client into one implementation per
+// protocol instead of a single HTTP-only downloader.
+func fetcherFor(ref string) (Fetcher, error) {
+	switch {
+	case strings.HasPrefix(ref, "s3://"):
+		return s3Fetcher{}, nil
+	case strings.HasPrefix(ref, "gs://"):
+		return gsFetcher{}, nil
+	case strings.HasPrefix(ref, "file://") || !strings.Contains(ref, "://"):
+		return fileFetcher{}, nil
+	case strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://"):
+		return httpFetcher{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported bundle URL scheme: %q", ref)
+	}
+}
+
+// httpFetcher downloads over plain HTTP(S); it's the existing
+// httpGetToWriter path wrapped behind the Fetcher interface.
+type httpFetcher struct{}
+
+func (httpFetcher) Fetch(ctx context.Context, ref, dst string) (string, error) {
+	return httpGetToFileVerified(ctx, ref, dst)
+}
+
+// FetchConditional is the cache-aware counterpart to Fetch: it sends
+// If-None-Match/If-Modified-Since from prev (when known) so an unchanged
+// remote bundle costs a single round trip, and resumes a previous partial
+// download via Range when dst+".part" already has bytes on disk. The
+// result is only renamed into place once the whole body has been read and
+// hashed, so a crash mid-download leaves ".part" behind instead of a
+// truncated dst.
+func (httpFetcher) FetchConditional(ctx context.Context, ref, dst string, prev *urlIndexEntry) (idx urlIndexEntry, notModified bool, err error) {
+	partPath := dst + ".part"
+	var resumeFrom int64
+	if st, statErr := os.Stat(partPath); statErr == nil {
+		resumeFrom = st.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref, http.NoBody)
+	if err != nil {
+		return urlIndexEntry{}, false, err
+	}
+	if prev != nil {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+	}
+	if resumeFrom > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeFrom))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return urlIndexEntry{}, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return *prev, true, nil
+	case http.StatusPartialContent:
+		// keep resumeFrom, append below
+	case http.StatusOK:
+		resumeFrom = 0 // server ignored Range (or there was nothing to resume); start over
+	default:
+		return urlIndexEntry{}, false, fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if resumeFrom > 0 {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return urlIndexEntry{}, false, err
+	}
+	_, cpErr := io.Copy(f, io.LimitReader(resp.Body, maxExtractBytes+1))
+	clErr := f.Close()
+	if cpErr != nil {
+		return urlIndexEntry{}, false, cpErr
+	}
+	if clErr != nil {
+		return urlIndexEntry{}, false, clErr
+	}
+
+	st, err := os.Stat(partPath)
+	if err != nil {
+		return urlIndexEntry{}, false, err
+	}
+	if st.Size() > maxExtractBytes {
+		_ = os.Remove(partPath)
+		return urlIndexEntry{}, false, fmt.Errorf("downloaded bundle exceeds %d byte limit", maxExtractBytes)
+	}
+
+	sum, err := sha256OfFile(partPath)
+	if err != nil {
+		return urlIndexEntry{}, false, err
+	}
+	if err := os.Rename(partPath, dst); err != nil {
+		return urlIndexEntry{}, false, err
+	}
+
+	return urlIndexEntry{
+		SHA256:       sum,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, false, nil
+}
+
+func sha256OfFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileFetcher copies a local path (bare, or file://-prefixed) to dst, for
+// operators staging the bundle on a shared volume instead of a remote
+// mirror.
+type fileFetcher struct{}
+
+func (fileFetcher) Fetch(_ context.Context, ref, dst string) (string, error) {
+	src := strings.TrimPrefix(ref, "file://")
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	_, cpErr := io.Copy(io.MultiWriter(out, hasher), in)
+	clErr := out.Close()
+	if cpErr != nil {
+		return "", cpErr
+	}
+	if clErr != nil {
+		return "", clErr
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// s3Fetcher downloads s3://bucket/key using whatever credentials/region the
+// ambient AWS SDK config resolves, so operators can host bundles on an
+// internal, access-controlled mirror instead of a public GitHub Release.
+type s3Fetcher struct{}
+
+func (s3Fetcher) Fetch(ctx context.Context, ref, dst string) (string, error) {
+	bucket, key, err := parseS3Ref(ref)
+	if err != nil {
+		return "", err
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return "", fmt.Errorf("load aws config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg)
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		return "", fmt.Errorf("s3 get-object %s: %w", ref, err)
+	}
+	defer out.Body.Close()
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	_, cpErr := io.Copy(io.MultiWriter(f, hasher), out.Body)
+	clErr := f.Close()
+	if cpErr != nil {
+		return "", cpErr
+	}
+	if clErr != nil {
+		return "", clErr
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func parseS3Ref(ref string) (bucket, key string, err error) {
+	rest := strings.TrimPrefix(ref, "s3://")
+	bucket, key, ok := strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid s3 ref %q, want s3://bucket/key", ref)
+	}
+	return bucket, key, nil
+}
+
+// gsFetcher is a placeholder: this plugin has no Google Cloud Storage SDK
+// dependency today, so gs:// refs fail fast with a clear error instead of
+// silently falling through to another scheme.
+type gsFetcher struct{}
+
+func (gsFetcher) Fetch(context.Context, string, string) (string, error) {
+	return "", fmt.Errorf("gs:// bundle refs are not supported yet (no GCS SDK dependency); host the bundle over https:// or s3:// instead")
+}
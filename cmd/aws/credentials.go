@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// CredentialProfile is one config-supplied, ~/.aws/credentials-style static
+// profile, for environments where IRSA/IMDS aren't available and secrets
+// are injected by some other mechanism (e.g. a mounted Secret).
+type CredentialProfile struct {
+	AccessKeyID     string `yaml:"accessKeyId,omitempty"`
+	SecretAccessKey string `yaml:"secretAccessKey,omitempty"`
+	SessionToken    string `yaml:"sessionToken,omitempty"`
+}
+
+// CredentialsConfig selects and configures the credential provider chain
+// consulted before buildEnv, ahead of any explicit AssumeRole profile.
+type CredentialsConfig struct {
+	Providers          []string                     `yaml:"providers,omitempty"` // irsa, imds, profile, env
+	NamedProfiles      map[string]CredentialProfile `yaml:"namedProfiles,omitempty"`
+	ActiveNamedProfile string                       `yaml:"activeNamedProfile,omitempty"`
+}
+
+// credProvider resolves a set of temporary or static AWS credentials. "env"
+// is handled specially by resolveCredentialsEnv and has no credProvider
+// implementation: it means "use whatever the child process inherits".
+type credProvider interface {
+	resolve(ctx context.Context, cfg Config) (roleCredEntry, error)
+}
+
+var credentialProviders = map[string]credProvider{
+	"irsa":    irsaProvider{},
+	"imds":    imdsProvider{},
+	"profile": namedProfileProvider{},
+}
+
+const credsCacheTTLBuffer = 5 * time.Minute
+
+// resolveCredentialsEnv walks cfg.Credentials.Providers in priority order
+// and returns the env entries for the first provider that yields
+// non-expired credentials, along with that provider's name (used to target
+// cache invalidation on a 401/ExpiredToken retry). An empty providerName
+// with a nil error means "env": let the child inherit ambient credentials.
+func resolveCredentialsEnv(ctx context.Context, cfg Config) (env []string, providerName string, err error) {
+	providers := cfg.Credentials.Providers
+	if len(providers) == 0 {
+		providers = []string{"env"}
+	}
+	var lastErr error
+	for _, name := range providers {
+		if name == "env" {
+			return nil, "", nil
+		}
+		p, ok := credentialProviders[name]
+		if !ok {
+			lastErr = fmt.Errorf("unknown credential provider %q", name)
+			continue
+		}
+		entry, rerr := resolveCachedProvider(ctx, cfg, name, p)
+		if rerr != nil {
+			lastErr = rerr
+			continue
+		}
+		return credEnv(entry), name, nil
+	}
+	if lastErr != nil {
+		return nil, "", fmt.Errorf("no credential provider succeeded: %w", lastErr)
+	}
+	return nil, "", nil
+}
+
+// isExpiredCredsError reports whether runErr/outStr looks like the sort of
+// STS/IAM failure that a credential refresh could fix.
+func isExpiredCredsError(runErr error, outStr string) bool {
+	if runErr == nil {
+		return false
+	}
+	haystack := outStr
+	if runErr != nil {
+		haystack += " " + runErr.Error()
+	}
+	for _, marker := range []string{"ExpiredToken", "RequestExpired", "InvalidClientTokenId", "401"} {
+		if strings.Contains(haystack, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+type cachedCreds struct {
+	AccessKeyID     string    `json:"accessKeyId"`
+	SecretAccessKey string    `json:"secretAccessKey"`
+	SessionToken    string    `json:"sessionToken"`
+	Expiration      time.Time `json:"expiration"`
+}
+
+func credsCachePath(providerName string) (string, error) {
+	depsRoot, err := depsDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(depsRoot, "creds")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(providerName))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func readCachedCreds(path string) (roleCredEntry, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return roleCredEntry{}, false
+	}
+	var c cachedCreds
+	if err := json.Unmarshal(b, &c); err != nil {
+		return roleCredEntry{}, false
+	}
+	return roleCredEntry{
+		accessKeyID:     c.AccessKeyID,
+		secretAccessKey: c.SecretAccessKey,
+		sessionToken:    c.SessionToken,
+		expiration:      c.Expiration,
+	}, true
+}
+
+func writeCachedCreds(path string, entry roleCredEntry) {
+	c := cachedCreds{
+		AccessKeyID:     entry.accessKeyID,
+		SecretAccessKey: entry.secretAccessKey,
+		SessionToken:    entry.sessionToken,
+		Expiration:      entry.expiration,
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, b, 0o600)
+}
+
+// invalidateProviderCache drops the on-disk cache entry for name so the next
+// resolveCredentialsEnv call re-runs the exchange instead of reusing what
+// just failed.
+func invalidateProviderCache(name string) {
+	path, err := credsCachePath(name)
+	if err != nil {
+		return
+	}
+	_ = os.Remove(path)
+}
+
+func resolveCachedProvider(ctx context.Context, cfg Config, name string, p credProvider) (roleCredEntry, error) {
+	path, err := credsCachePath(name)
+	if err != nil {
+		return roleCredEntry{}, err
+	}
+	if entry, ok := readCachedCreds(path); ok && time.Now().Add(credsCacheTTLBuffer).Before(entry.expiration) {
+		return entry, nil
+	}
+	entry, err := p.resolve(ctx, cfg)
+	if err != nil {
+		return roleCredEntry{}, fmt.Errorf("%s: %w", name, err)
+	}
+	writeCachedCreds(path, entry)
+	return entry, nil
+}
+
+// irsaProvider exchanges the EKS pod-identity-webhook's projected web
+// identity token for role credentials, the same mechanism IRSA uses for any
+// other AWS SDK running in the pod.
+type irsaProvider struct{}
+
+func (irsaProvider) resolve(ctx context.Context, cfg Config) (roleCredEntry, error) {
+	roleArn := os.Getenv("AWS_ROLE_ARN")
+	tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if roleArn == "" || tokenFile == "" {
+		return roleCredEntry{}, fmt.Errorf("AWS_ROLE_ARN/AWS_WEB_IDENTITY_TOKEN_FILE not set")
+	}
+	sessionName := sanitizeSessionName("botkube-" + pluginName + "-irsa")
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return roleCredEntry{}, fmt.Errorf("load aws config: %w", err)
+	}
+	client := sts.NewFromConfig(awsCfg)
+	ar := AssumeRoleConfig{RoleArn: roleArn, WebIdentityTokenFile: tokenFile, SessionName: sessionName}
+	return doAssumeRole(ctx, client, ar, sessionName)
+}
+
+// imdsProvider fetches role credentials straight from the instance metadata
+// service (IMDSv2) for plain EC2 hosts with no pod identity webhook.
+type imdsProvider struct{}
+
+const imdsBaseURL = "http://169.254.169.254/latest"
+
+func (imdsProvider) resolve(ctx context.Context, cfg Config) (roleCredEntry, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	tokReq, err := http.NewRequestWithContext(ctx, http.MethodPut, imdsBaseURL+"/api/token", http.NoBody)
+	if err != nil {
+		return roleCredEntry{}, err
+	}
+	tokReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	tokResp, err := client.Do(tokReq)
+	if err != nil {
+		return roleCredEntry{}, fmt.Errorf("imds token: %w", err)
+	}
+	tokBytes, err := io.ReadAll(tokResp.Body)
+	tokResp.Body.Close()
+	if err != nil {
+		return roleCredEntry{}, fmt.Errorf("imds token: %w", err)
+	}
+	token := strings.TrimSpace(string(tokBytes))
+
+	roleName, err := imdsGet(ctx, client, token, "/meta-data/iam/security-credentials/")
+	if err != nil {
+		return roleCredEntry{}, err
+	}
+	roleName = strings.TrimSpace(roleName)
+	if roleName == "" {
+		return roleCredEntry{}, fmt.Errorf("imds: no instance profile attached")
+	}
+
+	body, err := imdsGet(ctx, client, token, "/meta-data/iam/security-credentials/"+roleName)
+	if err != nil {
+		return roleCredEntry{}, err
+	}
+
+	var payload struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		return roleCredEntry{}, fmt.Errorf("imds: parse credentials: %w", err)
+	}
+	return roleCredEntry{
+		accessKeyID:     payload.AccessKeyID,
+		secretAccessKey: payload.SecretAccessKey,
+		sessionToken:    payload.Token,
+		expiration:      payload.Expiration,
+	}, nil
+}
+
+func imdsGet(ctx context.Context, client *http.Client, token, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsBaseURL+path, http.NoBody)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("imds %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("imds %s: bad status %s", path, resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// namedProfileProvider resolves a static, config-supplied credential set by
+// name, for setups where secrets are injected some other way (e.g. a
+// mounted Kubernetes Secret merged into the plugin config).
+type namedProfileProvider struct{}
+
+func (namedProfileProvider) resolve(_ context.Context, cfg Config) (roleCredEntry, error) {
+	name := cfg.Credentials.ActiveNamedProfile
+	if name == "" {
+		return roleCredEntry{}, fmt.Errorf("credentials.activeNamedProfile not set")
+	}
+	p, ok := cfg.Credentials.NamedProfiles[name]
+	if !ok {
+		return roleCredEntry{}, fmt.Errorf("unknown named profile %q", name)
+	}
+	return roleCredEntry{
+		accessKeyID:     p.AccessKeyID,
+		secretAccessKey: p.SecretAccessKey,
+		sessionToken:    p.SessionToken,
+		// Static profiles don't expire on their own; cache them for a long,
+		// fixed window so rotation still eventually takes effect.
+		expiration: time.Now().Add(24 * time.Hour),
+	}, nil
+}
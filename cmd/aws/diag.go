@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// matchDiagCommand recognizes `helper diag`/`helper support`/`helper dump`
+// (and any of their trailing flags, e.g. `--stdout`).
+func matchDiagCommand(cmdLine string) (rest string, ok bool) {
+	for _, alias := range []string{"helper diag", "helper support", "helper dump"} {
+		if cmdLine == alias {
+			return "", true
+		}
+		if r, cut := strings.CutPrefix(cmdLine, alias+" "); cut {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// cmdHistoryEntry records one past `aws ...` invocation for the ring buffer
+// surfaced by `helper diag`.
+type cmdHistoryEntry struct {
+	cmdLine  string
+	err      string
+	duration time.Duration
+	at       time.Time
+}
+
+const maxCmdHistory = 20
+
+// recordHistory appends an executed command to e's ring buffer, trimming it
+// to maxCmdHistory entries.
+func (e *Executor) recordHistory(cmdLine string, dur time.Duration, runErr error) {
+	entry := cmdHistoryEntry{cmdLine: cmdLine, duration: dur, at: time.Now()}
+	if runErr != nil {
+		entry.err = runErr.Error()
+	}
+	e.historyMu.Lock()
+	defer e.historyMu.Unlock()
+	e.history = append(e.history, entry)
+	if len(e.history) > maxCmdHistory {
+		e.history = e.history[len(e.history)-maxCmdHistory:]
+	}
+}
+
+func (e *Executor) historySnapshot() []cmdHistoryEntry {
+	e.historyMu.Lock()
+	defer e.historyMu.Unlock()
+	out := make([]cmdHistoryEntry, len(e.history))
+	copy(out, e.history)
+	return out
+}
+
+// depsListing walks depsDir() and reports each entry's size, for
+// troubleshooting a pod where the bundle or kubeconfig cache looks wrong.
+func depsListing() (string, error) {
+	depsRoot, err := depsDir()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	walkErr := filepath.Walk(depsRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			fmt.Fprintf(&b, "%s: error: %v\n", path, err)
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(depsRoot, path)
+		fmt.Fprintf(&b, "%10d  %s  %s\n", info.Size(), info.Mode(), rel)
+		return nil
+	})
+	if walkErr != nil && !os.IsNotExist(walkErr) {
+		return b.String(), walkErr
+	}
+	if b.Len() == 0 {
+		return "(empty or not yet populated)\n", nil
+	}
+	return b.String(), nil
+}
+
+// sanitizedConfigText renders cfg for display with anything that could
+// plausibly carry a secret (free-form env values) redacted.
+func sanitizedConfigText(cfg Config) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "defaultRegion: %s\n", cfg.DefaultRegion)
+	fmt.Fprintf(&b, "allowed: %v\n", cfg.Allowed)
+	fmt.Fprintf(&b, "policy rules: %d\n", len(cfg.Policy))
+	fmt.Fprintf(&b, "profiles: %d\n", len(cfg.Profiles))
+	fmt.Fprintf(&b, "templates: %d\n", len(cfg.Templates))
+	fmt.Fprintf(&b, "assumeRole.roleArn: %s\n", cfg.AssumeRole.RoleArn)
+	fmt.Fprintf(&b, "artifacts.bucket: %s\n", cfg.Artifacts.Bucket)
+	fmt.Fprintf(&b, "env:\n")
+	for k := range cfg.Env {
+		fmt.Fprintf(&b, "  %s: ***\n", k)
+	}
+	return b.String()
+}
+
+// fullDiagText assembles the `helper diag`/`helper support`/`helper dump`
+// support bundle: plugin/runtime info, the deps directory listing, bundle
+// verification state, `aws --version`, a redacted Config, and the recent
+// command/error history.
+func (e *Executor) fullDiagText(ctx context.Context, cfg Config) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "=== plugin ===\n")
+	fmt.Fprintf(&b, "os/arch: %s/%s\n\n", runtime.GOOS, runtime.GOARCH)
+
+	fmt.Fprintf(&b, "=== bundle ===\n%s\n", bundleDiagSection(cfg))
+
+	if awsBin, glibcDir, distDir, err := prepareAws(ctx, cfg); err != nil {
+		fmt.Fprintf(&b, "aws --version: unavailable (%v)\n\n", err)
+	} else {
+		ld := resolveLoaderPath(glibcDir)
+		libraryPath := buildLDPath(glibcDir, distDir)
+		env := buildEnv(cfg, libraryPath)
+		out, verr := runAWS(ctx, ld, awsBin, libraryPath, []string{"--version"}, env)
+		if verr != nil {
+			fmt.Fprintf(&b, "aws --version: error: %v\n\n", verr)
+		} else {
+			fmt.Fprintf(&b, "aws --version: %s\n\n", strings.TrimSpace(string(out)))
+		}
+	}
+
+	fmt.Fprintf(&b, "=== deps directory ===\n")
+	listing, lerr := depsListing()
+	if lerr != nil {
+		fmt.Fprintf(&b, "error listing deps dir: %v\n", lerr)
+	} else {
+		b.WriteString(listing)
+	}
+	b.WriteString("\n")
+
+	fmt.Fprintf(&b, "=== config (redacted) ===\n%s\n", sanitizedConfigText(cfg))
+
+	fmt.Fprintf(&b, "=== recent commands ===\n")
+	for _, h := range e.historySnapshot() {
+		status := "ok"
+		if h.err != "" {
+			status = "error: " + h.err
+		}
+		fmt.Fprintf(&b, "%s  %8s  %-8s  %s\n", h.at.Format(time.RFC3339), h.duration.Round(time.Millisecond), status, h.cmdLine)
+	}
+	b.WriteString("\n")
+
+	fmt.Fprintf(&b, "=== recent bundle errors ===\n")
+	for _, be := range recentBundleErrors() {
+		fmt.Fprintf(&b, "%s\n", be)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+)
+
+type dockerAuthEntry struct {
+	Auth string `json:"auth"`
+}
+
+type dockerConfig struct {
+	Auths map[string]dockerAuthEntry `json:"auths"`
+}
+
+func dockerConfigPath() (string, error) {
+	depsRoot, err := depsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(depsRoot, "docker", "config.json"), nil
+}
+
+func readDockerConfig(path string) (dockerConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return dockerConfig{}, nil
+		}
+		return dockerConfig{}, err
+	}
+	var cfg dockerConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return dockerConfig{}, err
+	}
+	return cfg, nil
+}
+
+// ecrLogin exchanges the credentials in env (or, when env carries no static
+// keys, the SDK's default credential chain) for an ECR authorization token
+// and writes it into a docker config.json `auths` entry, the same outcome
+// as `aws ecr get-login-password | docker login --password-stdin` without
+// shelling out to either binary.
+func ecrLogin(ctx context.Context, env []string, registry string) (string, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	creds := credsFromEnv(env)
+	if c, _ := creds.Retrieve(ctx); c.AccessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(creds))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return "", fmt.Errorf("load aws config: %w", err)
+	}
+	client := ecr.NewFromConfig(awsCfg)
+	out, err := client.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return "", fmt.Errorf("ecr get-authorization-token: %w", err)
+	}
+	if len(out.AuthorizationData) == 0 {
+		return "", fmt.Errorf("ecr: no authorization data returned")
+	}
+	authData := out.AuthorizationData[0]
+	token := aws.ToString(authData.AuthorizationToken)
+	if registry == "" {
+		registry = strings.TrimPrefix(aws.ToString(authData.ProxyEndpoint), "https://")
+	}
+
+	path, err := dockerConfigPath()
+	if err != nil {
+		return "", err
+	}
+	cfg, err := readDockerConfig(path)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Auths == nil {
+		cfg.Auths = map[string]dockerAuthEntry{}
+	}
+	cfg.Auths[registry] = dockerAuthEntry{Auth: token}
+
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return "", err
+	}
+	return registry, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runEKSTokenMode re-invokes this plugin binary outside the go-plugin RPC
+// handshake so it can act as a kubectl exec credential plugin: it resolves
+// the same AssumeRole credential chain the executor itself uses for
+// `--role`, then shells out to `aws eks get-token`, forwarding its
+// ExecCredential JSON straight to stdout for kubectl to consume. Entered via
+// `<this-binary> __eks-token --cluster <name> [--region <r>] [--role <arn>]
+// [--session <name>]`, the args buildExecKubeconfig bakes into the
+// kubeconfig's users[].exec stanza.
+func runEKSTokenMode(args []string) int {
+	flags := parseFlagPairs(args)
+	cluster := flags["cluster"]
+	if cluster == "" {
+		fmt.Fprintln(os.Stderr, "__eks-token: --cluster is required")
+		return 1
+	}
+	region := flags["region"]
+
+	ctx := context.Background()
+	var cfg Config
+	ar := AssumeRoleConfig{RoleArn: flags["role"], SessionName: flags["session"]}
+	roleEnv, err := assumeRoleEnv(ctx, ar, "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "__eks-token: assume role: "+err.Error())
+		return 1
+	}
+
+	awsBin, glibcDir, distDir, err := prepareAws(ctx, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "__eks-token: prepare aws cli: "+err.Error())
+		return 1
+	}
+	ld := resolveLoaderPath(glibcDir)
+	libraryPath := buildLDPath(glibcDir, distDir)
+	env := append(buildEnv(cfg, libraryPath), roleEnv...)
+
+	tokenArgs := []string{"eks", "get-token", "--cluster-name", cluster}
+	if region != "" {
+		tokenArgs = append(tokenArgs, "--region", region)
+	}
+	out, terr := runAWS(ctx, ld, awsBin, libraryPath, tokenArgs, env)
+	if terr != nil {
+		fmt.Fprintln(os.Stderr, "__eks-token: get-token: "+terr.Error()+": "+strings.TrimSpace(string(out)))
+		return 1
+	}
+	os.Stdout.Write(out)
+	return 0
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/shlex"
 	"github.com/kubeshop/botkube/pkg/api"
@@ -20,7 +21,68 @@ func (e *Executor) Metadata(context.Context) (api.MetadataOutput, error) {
         "defaultRegion":{"type":"string"},
         "prependArgs":{"type":"array","items":{"type":"string"}},
         "allowed":{"type":"array","items":{"type":"string"}},
-        "env":{"type":"object","additionalProperties":{"type":"string"}}
+        "env":{"type":"object","additionalProperties":{"type":"string"}},
+        "bundleVerify":{
+          "type":"object",
+          "properties":{
+            "sha256Amd64":{"type":"string"},
+            "sha256Arm64":{"type":"string"},
+            "sigUrlAmd64":{"type":"string"},
+            "sigUrlArm64":{"type":"string"},
+            "pubKey":{"type":"string"}
+          },
+          "additionalProperties": false
+        },
+        "assumeRole":{"type":"object"},
+        "profiles":{"type":"object"},
+        "streaming":{"type":"object"},
+        "artifacts":{"type":"object"},
+        "templates":{"type":"object"},
+        "policy":{
+          "type":"array",
+          "items":{
+            "type":"object",
+            "properties":{
+              "match":{"type":"string"},
+              "classify":{"type":"string","enum":["read","write","destructive"]},
+              "effect":{"type":"string","enum":["allow","deny"]},
+              "reason":{"type":"string"},
+              "requireProfile":{"type":"array","items":{"type":"string"}},
+              "requireApproval":{"type":"boolean"}
+            },
+            "additionalProperties": false
+          }
+        },
+        "approvers":{"type":"array","items":{"type":"string"}},
+        "output":{
+          "type":"object",
+          "properties":{
+            "maxInlineBytes":{"type":"integer"},
+            "truncateStrategy":{"type":"string","enum":["head","tail","middle"]},
+            "metaMaxBytes":{"type":"integer"}
+          },
+          "additionalProperties": false
+        },
+        "credentials":{
+          "type":"object",
+          "properties":{
+            "providers":{"type":"array","items":{"type":"string","enum":["irsa","imds","profile","env"]}},
+            "namedProfiles":{"type":"object"},
+            "activeNamedProfile":{"type":"string"}
+          },
+          "additionalProperties": false
+        },
+        "installMode":{"type":"string","enum":["tarball","system","auto"]},
+        "systemPackage":{
+          "type":"object",
+          "properties":{
+            "urlAmd64":{"type":"string"},
+            "urlArm64":{"type":"string"},
+            "sha256Amd64":{"type":"string"},
+            "sha256Arm64":{"type":"string"}
+          },
+          "additionalProperties": false
+        }
       },
       "additionalProperties": false
     }`
@@ -51,24 +113,259 @@ func (e *Executor) Execute(ctx context.Context, in executor.ExecuteInput) (execu
 		return executor.ExecuteOutput{Message: api.NewCodeBlockMessage(fullHelpText(), true)}, nil
 	}
 
-	// Normalize command, check allowed list, apply prepend args
+	// The chat user who triggered this command, used as the default STS
+	// session name on any AssumeRole call below so CloudTrail shows who
+	// initiated it instead of a single generic session name.
+	userHint := sessionNameForUser(in.Context.Message.User.Mention, in.Context.Message.User.DisplayName)
+
+	// Normalize command, resolve an optional `--as <profile>` override, check
+	// the effective allowed list, and apply prepend args.
 	cmdLine := normalizeCmd(raw)
-	if len(cfg.Allowed) > 0 && !isAllowed(cmdLine, cfg.Allowed) {
+	profileName, cmdLine := extractProfileOverride(cmdLine)
+	cfg.pinOverride, cmdLine = extractPinOverride(cmdLine)
+	assumeRole, allowed := resolveProfile(cfg, profileName)
+
+	// An approved command skips straight past the policy gate: the rule
+	// that granted it already ran once when requireApproval fired.
+	if tok, ok := strings.CutPrefix(cmdLine, "_approve "); ok {
+		approvedCmd, aerr := consumeApprovalToken(strings.TrimSpace(tok), cfg.Approvers, in.Context.Message.User.Mention, in.Context.Message.User.DisplayName)
+		if aerr != nil {
+			return msg(aerr.Error()), nil
+		}
+		cmdLine = approvedCmd
+	} else if len(cfg.Policy) > 0 {
+		decision, perr := evaluatePolicy(cfg.Policy, cmdLine, profileName)
+		if perr != nil {
+			return msg(perr.Error()), nil
+		}
+		if !decision.allowed {
+			reason := "denied by policy"
+			if decision.reason != "" {
+				reason = decision.reason
+			}
+			return msg(fmt.Sprintf("Command not allowed: %q (%s)", cmdLine, reason)), nil
+		}
+		if decision.requireApproval {
+			token := newApprovalToken(cmdLine)
+			return executor.ExecuteOutput{Message: api.Message{
+				Sections: []api.Section{{
+					Base: api.Base{
+						Header:      "Approval required",
+						Description: fmt.Sprintf("%q requires sign-off from one of: %s (clicker identity is checked against this list, but only as a string match -- see help for the approvers caveat)", cmdLine, strings.Join(cfg.Approvers, ", ")),
+					},
+					Buttons: []api.Button{
+						api.NewMessageButtonBuilder().ForCommandWithDescCmd("Approve", "aws _approve "+token),
+					},
+				}},
+			}}, nil
+		}
+	} else if len(allowed) > 0 && !isAllowed(cmdLine, allowed) {
 		return msg(fmt.Sprintf("Command not allowed: %q", cmdLine)), nil
 	}
 	if len(cfg.PrependArgs) > 0 {
 		cmdLine = strings.Join(append(append([]string{}, cfg.PrependArgs...), cmdLine), " ")
 	}
+	// Cancel a previously started streaming command by its short id. In
+	// practice this is unreachable from chat today: runAWSStreaming blocks
+	// on cmd.Wait() and unregisters id before Execute can return the
+	// message that reveals it (see the "Command finished" section below),
+	// so there is no window in which a caller both knows id and can still
+	// cancel it. Left in place for a caller that already has the id some
+	// other way (e.g. scripted against this same id space).
+	if id, ok := strings.CutPrefix(cmdLine, "cancel "); ok {
+		if err := cancelRunning(strings.TrimSpace(id)); err != nil {
+			return msg(err.Error()), nil
+		}
+		return msg("cancel requested for " + strings.TrimSpace(id)), nil
+	}
+
+	// `aws gc [--ttl <duration>]` prunes bundle cache entries (see
+	// bundlecache.go) other than the one depsDir()/current points at.
+	if cmdLine == "gc" || strings.HasPrefix(cmdLine, "gc ") {
+		ttl := 7 * 24 * time.Hour
+		if v := parseFlagPairs(strings.Fields(cmdLine))["ttl"]; v != "" {
+			d, perr := time.ParseDuration(v)
+			if perr != nil {
+				return msg("invalid --ttl: " + perr.Error()), nil
+			}
+			ttl = d
+		}
+		depsRoot, derr := depsDir()
+		if derr != nil {
+			return msg(derr.Error()), nil
+		}
+		res, gerr := runBundleGC(depsRoot, ttl)
+		if gerr != nil {
+			return msg(gerr.Error()), nil
+		}
+		if len(res.Pruned) == 0 {
+			return msg(fmt.Sprintf("gc: nothing to prune (kept current: %s)", res.KeptCurrent)), nil
+		}
+		return msg(fmt.Sprintf("gc: pruned %d entr(ies), freed %d bytes, kept current: %s\n%s",
+			len(res.Pruned), res.FreedBytes, res.KeptCurrent, strings.Join(res.Pruned, "\n"))), nil
+	}
+
+	// A submitted parameter form renders into the final aws invocation and
+	// is then run back through the same policy gate (or allow-list, if no
+	// policy is configured) as the main dispatch path above: the unrendered
+	// template name never carries the instance id/params the user actually
+	// picked, so this is the first point those are available to evaluate.
+	if tplID, params, ok := parseFormSubmit(cmdLine); ok {
+		tpl, tok := allTemplates(cfg)[tplID]
+		if !tok {
+			return msg(fmt.Sprintf("unknown template %q", tplID)), nil
+		}
+		if _, incomplete := nextTemplateParam(tpl, params); incomplete {
+			// Not every parameter has been picked yet: re-render the form
+			// for the next one, carrying forward what's already selected
+			// instead of hard-erroring on the missing ones.
+			ld, awsBin, libraryPath, env, ferr := templateFormEnv(ctx, cfg, assumeRole, userHint)
+			if ferr != nil {
+				return msg(ferr.Error()), nil
+			}
+			form, ferr := renderTemplateForm(ctx, cfg, tplID, tpl, ld, awsBin, libraryPath, env, params)
+			if ferr != nil {
+				return msg("failed to build form: " + ferr.Error()), nil
+			}
+			return executor.ExecuteOutput{Message: form}, nil
+		}
+		rendered, terr := renderTemplate(cfg, tplID, params)
+		if terr != nil {
+			return msg(terr.Error()), nil
+		}
+		if len(cfg.Policy) > 0 {
+			decision, perr := evaluatePolicy(cfg.Policy, rendered, profileName)
+			if perr != nil {
+				return msg(perr.Error()), nil
+			}
+			if !decision.allowed {
+				reason := "denied by policy"
+				if decision.reason != "" {
+					reason = decision.reason
+				}
+				return msg(fmt.Sprintf("Command not allowed: %q (%s)", rendered, reason)), nil
+			}
+			if decision.requireApproval {
+				token := newApprovalToken(rendered)
+				return executor.ExecuteOutput{Message: api.Message{
+					Sections: []api.Section{{
+						Base: api.Base{
+							Header:      "Approval required",
+							Description: fmt.Sprintf("%q requires sign-off from one of: %s (clicker identity is checked against this list, but only as a string match -- see help for the approvers caveat)", rendered, strings.Join(cfg.Approvers, ", ")),
+						},
+						Buttons: []api.Button{
+							api.NewMessageButtonBuilder().ForCommandWithDescCmd("Approve", "aws _approve "+token),
+						},
+					}},
+				}}, nil
+			}
+		} else if len(allowed) > 0 && !isAllowed(rendered, allowed) {
+			return msg(fmt.Sprintf("Command not allowed: %q", rendered)), nil
+		}
+		cmdLine = rendered
+	} else if tplID, tpl, ok := matchTemplate(cfg, cmdLine); ok {
+		// Parameterized update operations are never run directly; the user
+		// must pick concrete values from a live-populated form first.
+		ld, awsBin, libraryPath, env, ferr := templateFormEnv(ctx, cfg, assumeRole, userHint)
+		if ferr != nil {
+			return msg(ferr.Error()), nil
+		}
+		form, ferr := renderTemplateForm(ctx, cfg, tplID, tpl, ld, awsBin, libraryPath, env, nil)
+		if ferr != nil {
+			return msg("failed to build form: " + ferr.Error()), nil
+		}
+		return executor.ExecuteOutput{Message: form}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(cmdLine, "artifact show "); ok {
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return msg("usage: aws artifact show <uuid> [--page N]"), nil
+		}
+		page := 1
+		for i, f := range fields {
+			if f == "--page" && i+1 < len(fields) {
+				fmt.Sscanf(fields[i+1], "%d", &page)
+			}
+		}
+		const pageSize = 200
+		body, err := fetchArtifactPage(ctx, cfg.Artifacts, fields[0], page, pageSize)
+		if err != nil {
+			return msg(err.Error()), nil
+		}
+		return executor.ExecuteOutput{Message: api.NewCodeBlockMessage(body, true)}, nil
+	}
+
+	if rest, isDiag := matchDiagCommand(cmdLine); isDiag {
+		forceInline := strings.Contains(rest, "--stdout")
+		text := e.fullDiagText(ctx, cfg)
+		outCfg := cfg.Output.withDefaults()
+		if forceInline || len(text) <= outCfg.MaxInlineBytes {
+			return executor.ExecuteOutput{Message: api.NewCodeBlockMessage(text, true)}, nil
+		}
+		// No attachment API is used here; oversized bundles reuse the same
+		// local paging cache as regular command output.
+		runID, footer, perr := persistRunOutput(text)
+		if perr != nil {
+			return executor.ExecuteOutput{Message: api.NewCodeBlockMessage(text, true)}, nil
+		}
+		inline, _ := truncateForChat(text, outCfg)
+		out := executor.ExecuteOutput{Message: api.NewCodeBlockMessage(inline+"\n"+footer, true)}
+		out.Message.Sections = append(out.Message.Sections, api.Section{
+			Base: api.Base{Header: "Diagnostics bundle"},
+			Buttons: []api.Button{
+				api.NewMessageButtonBuilder().ForCommandWithDescCmd("Show more", "aws helper show "+runID),
+				api.NewMessageButtonBuilder().ForCommandWithDescCmd("Download", "aws helper download "+runID),
+			},
+		})
+		return out, nil
+	}
+
+	if rest, ok := strings.CutPrefix(cmdLine, "helper show "); ok {
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return msg("usage: aws helper show <id> [--page N]"), nil
+		}
+		page, err := fetchRunPage(fields[0], parsePageFlag(fields))
+		if err != nil {
+			return msg(err.Error()), nil
+		}
+		return executor.ExecuteOutput{Message: api.NewCodeBlockMessage(page, true)}, nil
+	}
+	if rest, ok := strings.CutPrefix(cmdLine, "helper download "); ok {
+		id := strings.Fields(rest)
+		if len(id) == 0 {
+			return msg("usage: aws helper download <id>"), nil
+		}
+		full, err := fetchRunFull(id[0])
+		if err != nil {
+			return msg(err.Error()), nil
+		}
+		// No attachment API is used here either (the SDK has none to use):
+		// this returns the same inline code block `helper show` does, just
+		// unpaginated.
+		return executor.ExecuteOutput{Message: api.NewCodeBlockMessage(full, true)}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(cmdLine, "kubeconfig "); ok {
+		return e.handleKubeconfigSubcommand(ctx, cfg, assumeRole, rest, userHint)
+	}
+
 	// Special helper commands
 	if strings.HasPrefix(cmdLine, "helper reboot-ec2") {
 		// Prepare AWS binary to query instance IDs
-		awsBin, glibcDir, distDir, err := prepareAws(ctx)
+		awsBin, glibcDir, distDir, err := prepareAws(ctx, cfg)
 		if err != nil {
 			return msg("failed to prepare aws cli: " + err.Error()), nil
 		}
 		ld := resolveLoaderPath(glibcDir)
 		libraryPath := buildLDPath(glibcDir, distDir)
 		env := buildEnv(cfg, libraryPath)
+		roleEnv, rerr := assumeRoleEnv(ctx, assumeRole, userHint)
+		if rerr != nil {
+			return msg("failed to assume role: " + rerr.Error()), nil
+		}
+		env = append(env, roleEnv...)
 
 		ids, qerr := listEC2InstanceIDs(ctx, ld, awsBin, libraryPath, env)
 		if qerr != nil {
@@ -95,13 +392,102 @@ func (e *Executor) Execute(ctx context.Context, in executor.ExecuteInput) (execu
 		}}, nil
 	}
 
+	if rest, ok := strings.CutPrefix(cmdLine, "helper eks-kubeconfig"); ok {
+		awsBin, glibcDir, distDir, err := prepareAws(ctx, cfg)
+		if err != nil {
+			return msg("failed to prepare aws cli: " + err.Error()), nil
+		}
+		ld := resolveLoaderPath(glibcDir)
+		libraryPath := buildLDPath(glibcDir, distDir)
+		env := buildEnv(cfg, libraryPath)
+		roleEnv, rerr := assumeRoleEnv(ctx, assumeRole, userHint)
+		if rerr != nil {
+			return msg("failed to assume role: " + rerr.Error()), nil
+		}
+		env = append(env, roleEnv...)
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			// No cluster picked yet: list clusters and offer a button per one.
+			clusters, qerr := listEKSClusters(ctx, ld, awsBin, libraryPath, env)
+			if qerr != nil {
+				return msg("failed to list clusters: " + qerr.Error()), nil
+			}
+			if len(clusters) == 0 {
+				return msg("no EKS clusters found"), nil
+			}
+			builder := api.NewMessageButtonBuilder()
+			buttons := make([]api.Button, 0, len(clusters))
+			for i, c := range clusters {
+				if i >= 30 {
+					break
+				}
+				buttons = append(buttons, builder.ForCommandWithDescCmd(c, "aws helper eks-kubeconfig "+c))
+			}
+			return executor.ExecuteOutput{Message: api.Message{
+				Sections: []api.Section{{
+					Base:    api.Base{Header: "Select cluster for kubeconfig"},
+					Buttons: buttons,
+				}},
+			}}, nil
+		}
+
+		cluster := fields[0]
+		region := cfg.DefaultRegion
+		for i, f := range fields {
+			if f == "--region" && i+1 < len(fields) {
+				region = fields[i+1]
+			}
+		}
+		path, werr := writeKubeconfig(ctx, ld, awsBin, libraryPath, env, cluster, region)
+		if werr != nil {
+			return msg("failed to update kubeconfig: " + werr.Error()), nil
+		}
+		return executor.ExecuteOutput{Message: api.Message{
+			Sections: []api.Section{{
+				Base: api.Base{
+					Header:      "kubeconfig ready for " + cluster,
+					Description: "Wrote " + path,
+				},
+				Buttons: []api.Button{
+					api.NewMessageButtonBuilder().ForCommandWithDescCmd(
+						"Open in kubectl plugin", "kubectl --kubeconfig "+path+" cluster-info",
+					),
+				},
+			}},
+		}}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(cmdLine, "helper ecr-login"); ok {
+		// No aws CLI binary invocation needed here: the ECR token exchange
+		// goes straight through the AWS SDK, reusing whatever credentials
+		// the rest of Execute would have used.
+		env := buildEnv(cfg, "")
+		credsEnv, _, cerr := resolveCredentialsEnv(ctx, cfg)
+		if cerr != nil {
+			return msg("failed to resolve credentials: " + cerr.Error()), nil
+		}
+		env = append(env, credsEnv...)
+		roleEnv, rerr := assumeRoleEnv(ctx, assumeRole, userHint)
+		if rerr != nil {
+			return msg("failed to assume role: " + rerr.Error()), nil
+		}
+		env = append(env, roleEnv...)
+
+		registry, lerr := ecrLogin(ctx, env, strings.TrimSpace(rest))
+		if lerr != nil {
+			return msg("ecr login failed: " + lerr.Error()), nil
+		}
+		return msg("logged in to " + registry + " (docker config written under depsDir()/docker)"), nil
+	}
+
 	args, err := shlex.Split(cmdLine)
 	if err != nil {
 		return msg("invalid arguments: " + err.Error()), nil
 	}
 
 	// Prepare AWS binary/loader
-	awsBin, glibcDir, distDir, err := prepareAws(ctx)
+	awsBin, glibcDir, distDir, err := prepareAws(ctx, cfg)
 	if err != nil {
 		return msg("failed to prepare aws cli: " + err.Error()), nil
 	}
@@ -109,9 +495,33 @@ func (e *Executor) Execute(ctx context.Context, in executor.ExecuteInput) (execu
 	libraryPath := buildLDPath(glibcDir, distDir)
 	env := buildEnv(cfg, libraryPath)
 
-	// Execute
-	out, runErr := runAWS(ctx, ld, awsBin, libraryPath, args, env)
-	outStr := strings.TrimSpace(string(out))
+	credsEnv, credsProvider, err := resolveCredentialsEnv(ctx, cfg)
+	if err != nil {
+		return msg("failed to resolve credentials: " + err.Error()), nil
+	}
+	env = append(env, credsEnv...)
+
+	roleEnv, err := assumeRoleEnv(ctx, assumeRole, userHint)
+	if err != nil {
+		return msg("failed to assume role: " + err.Error()), nil
+	}
+	env = append(env, roleEnv...)
+
+	// Execute under cfg.Streaming's hard timeout so long-running commands
+	// (logs tail, s3 sync, cloudformation deploy) don't block forever; the
+	// collected output comes back as one result and oversized output is
+	// uploaded or truncated below.
+	start := time.Now()
+	id, res, runErr := runAWSStreaming(ctx, ld, awsBin, libraryPath, args, env, cfg.Streaming)
+	if runErr != nil && credsProvider != "" && isExpiredCredsError(runErr, res.output) {
+		invalidateProviderCache(credsProvider)
+		if refreshedCredsEnv, _, rerr := resolveCredentialsEnv(ctx, cfg); rerr == nil {
+			env = append(append(buildEnv(cfg, libraryPath), refreshedCredsEnv...), roleEnv...)
+			id, res, runErr = runAWSStreaming(ctx, ld, awsBin, libraryPath, args, env, cfg.Streaming)
+		}
+	}
+	e.recordHistory(cmdLine, time.Since(start), runErr)
+	outStr := res.output
 	if runErr != nil {
 		dbg := fmt.Sprintf(
 			"DBG useLoader=%t ld=%q aws=%q glibcDir=%q distDir=%q",
@@ -125,5 +535,60 @@ func (e *Executor) Execute(ctx context.Context, in executor.ExecuteInput) (execu
 	if outStr == "" {
 		outStr = "(no output)"
 	}
-	return executor.ExecuteOutput{Message: api.NewCodeBlockMessage(outStr, true)}, nil
+	if res.truncated {
+		outStr += "\n" + sizeSummary(res)
+	}
+
+	art, uploaded, aerr := maybeUploadArtifact(ctx, cfg.Artifacts, outStr)
+	if aerr != nil {
+		outStr += "\n(artifact upload failed: " + aerr.Error() + ")"
+		uploaded = false
+	}
+
+	var out executor.ExecuteOutput
+	if uploaded {
+		out = executor.ExecuteOutput{Message: api.NewCodeBlockMessage(art.preview, true)}
+		out.Message.Sections = append(out.Message.Sections, api.Section{
+			Base: api.Base{
+				Header:      "Full output uploaded",
+				Description: "Output exceeded the inline size limit. Download: " + art.presignURL,
+			},
+			Buttons: []api.Button{
+				api.NewMessageButtonBuilder().ForCommandWithDescCmd("Show more", "aws artifact show "+art.uuid),
+			},
+		})
+	} else {
+		// No S3 artifact sink configured (or the upload failed): fall back to
+		// local disk so oversized output still has a paged "show more" path
+		// instead of just being chopped off.
+		outCfg := cfg.Output.withDefaults()
+		inline, truncated := truncateForChat(outStr, outCfg)
+		var extraSections []api.Section
+		if truncated {
+			runID, footer, perr := persistRunOutput(outStr)
+			if perr != nil {
+				inline += "\n(failed to persist full output: " + perr.Error() + ")"
+			} else {
+				inline += "\n" + footer
+				extraSections = append(extraSections, api.Section{
+					Base: api.Base{Header: "Output truncated"},
+					Buttons: []api.Button{
+						api.NewMessageButtonBuilder().ForCommandWithDescCmd("Show more", "aws helper show "+runID),
+						api.NewMessageButtonBuilder().ForCommandWithDescCmd("Download", "aws helper download "+runID),
+					},
+				})
+			}
+		}
+		out = executor.ExecuteOutput{Message: api.NewCodeBlockMessage(inline, true)}
+		out.Message.Sections = append(out.Message.Sections, extraSections...)
+	}
+	// runAWSStreaming is synchronous: by the time Execute can return this
+	// message, the command has already finished (or hit HardTimeout) and
+	// is no longer registered under id, so there is no window in which a
+	// "Cancel" button here could ever do anything. Report the id for
+	// correlation with `helper diag`/logs only, not as something cancelable.
+	out.Message.Sections = append(out.Message.Sections, api.Section{
+		Base: api.Base{Header: "Command finished (id " + id + ")"},
+	})
+	return out, nil
 }
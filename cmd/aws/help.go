@@ -34,7 +34,10 @@ func (e *Executor) Help(context.Context) (api.Message, error) {
 		btn.ForCommandWithDescCmd("Subnets", "aws ec2 describe-subnets"),
 	}
 	updates := []api.Button{
-		btn.ForCommandWithDescCmd("EC2 RebootInstances (picker)", "aws ec2 reboot-instances --instance-ids <i-xxxxxxxxxxxxxxxxx>"),
+		btn.ForCommandWithDescCmd("EC2 reboot instances", "aws ec2 reboot-instances"),
+		btn.ForCommandWithDescCmd("ASG start instance refresh", "aws autoscaling start-instance-refresh"),
+		btn.ForCommandWithDescCmd("EKS update nodegroup version", "aws eks update-nodegroup-version"),
+		btn.ForCommandWithDescCmd("RDS reboot DB instance", "aws rds reboot-db-instance"),
 	}
 
 	return api.Message{
@@ -54,10 +57,21 @@ func (e *Executor) Help(context.Context) (api.Message, error) {
 			{
 				Base: api.Base{
 					Header:      "Limited Update operations",
-					Description: "Operations may be restricted by policy.",
+					Description: "Typing any of these (with no arguments) opens an interactive form to pick the concrete target instead of running it directly; operations may also be restricted by policy.",
 				},
 				Buttons: updates,
 			},
+			{
+				Base: api.Base{
+					Header: "Approvals",
+					Description: "A policy rule with requireApproval: true posts an Approve button gated against " +
+						"approvers: by a plain, case-insensitive match on the clicking user's mention/display name -- " +
+						"not a real lookup against your chat platform's group or role membership. With approvers: set, " +
+						"if your platform doesn't relay the clicking user to this plugin, every Approve click is denied " +
+						"rather than silently accepted; with approvers: empty, every click is accepted. Treat requireApproval as a speed bump to slow down destructive commands, " +
+						"not as enforced `approvers:` group membership.",
+				},
+			},
 		},
 	}, nil
 }
@@ -89,5 +103,12 @@ Networking
 @black aws ec2 describe-subnets
 
 Limited Update operations
-@black aws ec2 reboot-instances --instance-ids <i-xxxxxxxxxxxxxxxxx>`)
+Type one of these with no arguments to open an interactive form and pick the concrete target; operations may also be restricted by policy.
+@black aws ec2 reboot-instances
+@black aws autoscaling start-instance-refresh
+@black aws eks update-nodegroup-version
+@black aws rds reboot-db-instance
+
+Approvals
+A policy rule with requireApproval: true posts an Approve button gated against approvers: by a plain, case-insensitive match on the clicking user's mention/display name -- not a real lookup against your chat platform's group or role membership. With approvers: set, if your platform doesn't relay the clicking user to this plugin, every Approve click is denied rather than silently accepted; with approvers: empty, every click is accepted. Treat requireApproval as a speed bump to slow down destructive commands, not as enforced approvers: group membership.`)
 }
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Install modes for Config.InstallMode.
+const (
+	InstallModeTarball = "tarball"
+	InstallModeSystem  = "system"
+	InstallModeAuto    = "auto"
+)
+
+// SystemPackageConfig pins the official AWS CLI v2 .deb/.rpm/.apk to install
+// when InstallMode is "system" or "auto" resolves to it.
+type SystemPackageConfig struct {
+	URLAmd64    string `yaml:"urlAmd64,omitempty"`
+	URLArm64    string `yaml:"urlArm64,omitempty"`
+	SHA256Amd64 string `yaml:"sha256Amd64,omitempty"`
+	SHA256Arm64 string `yaml:"sha256Arm64,omitempty"`
+}
+
+// packageManager describes one native package manager's local-file install
+// invocation, probed the way LURE checks for a host's supported managers.
+type packageManager struct {
+	name        string
+	probeBin    string
+	installArgs func(pkgPath string) []string
+}
+
+var packageManagers = []packageManager{
+	{name: "apt-get", probeBin: "apt-get", installArgs: func(p string) []string { return []string{"install", "-y", p} }},
+	{name: "dnf", probeBin: "dnf", installArgs: func(p string) []string { return []string{"install", "-y", p} }},
+	{name: "yum", probeBin: "yum", installArgs: func(p string) []string { return []string{"install", "-y", p} }},
+	{name: "apk", probeBin: "apk", installArgs: func(p string) []string { return []string{"add", "--allow-untrusted", p} }},
+	{name: "pacman", probeBin: "pacman", installArgs: func(p string) []string { return []string{"-U", "--noconfirm", p} }},
+	{name: "zypper", probeBin: "zypper", installArgs: func(p string) []string { return []string{"install", "-y", p} }},
+}
+
+func detectPackageManager() (packageManager, bool) {
+	for _, pm := range packageManagers {
+		if _, err := exec.LookPath(pm.probeBin); err == nil {
+			return pm, true
+		}
+	}
+	return packageManager{}, false
+}
+
+func detectElevationBinary() (string, bool) {
+	for _, bin := range []string{"sudo", "doas"} {
+		if _, err := exec.LookPath(bin); err == nil {
+			return bin, true
+		}
+	}
+	return "", false
+}
+
+// resolveInstallMode turns cfg.InstallMode into a concrete tarball/system
+// choice, resolving "auto" by probing the host. An unset mode resolves to
+// tarball without probing.
+func resolveInstallMode(cfg Config) string {
+	switch cfg.InstallMode {
+	case InstallModeSystem:
+		return InstallModeSystem
+	case InstallModeTarball:
+		return InstallModeTarball
+	case InstallModeAuto:
+		if _, ok := detectPackageManager(); ok {
+			if _, ok := detectElevationBinary(); ok {
+				return InstallModeSystem
+			}
+		}
+		return InstallModeTarball
+	default:
+		// Unset: keep the existing tarball behavior so deployments that
+		// predate this feature don't change behavior without opting in.
+		return InstallModeTarball
+	}
+}
+
+func systemPackageURLFor(cfg Config, arch string) string {
+	switch arch {
+	case "amd64":
+		return cfg.SystemPackage.URLAmd64
+	case "arm64":
+		return cfg.SystemPackage.URLArm64
+	default:
+		return ""
+	}
+}
+
+func systemPackageDigestFor(cfg Config, arch string) string {
+	switch arch {
+	case "amd64":
+		return cfg.SystemPackage.SHA256Amd64
+	case "arm64":
+		return cfg.SystemPackage.SHA256Arm64
+	default:
+		return ""
+	}
+}
+
+// ensureFromSystem installs the AWS CLI via the host's native package
+// manager instead of unpacking a self-contained tarball, for modern
+// distros where the glibc-shipping workaround isn't needed.
+func ensureFromSystem(ctx context.Context, cfg Config) (awsBin string, _ error) {
+	if path, err := exec.LookPath("aws"); err == nil {
+		return path, nil
+	}
+
+	pm, ok := detectPackageManager()
+	if !ok {
+		return "", fmt.Errorf("installMode=system: no supported package manager found (apt-get/dnf/yum/apk/pacman/zypper)")
+	}
+	elevate, ok := detectElevationBinary()
+	if !ok {
+		return "", fmt.Errorf("installMode=system: no privilege elevation binary found (sudo/doas)")
+	}
+
+	arch := runtime.GOARCH
+	pkgURL := systemPackageURLFor(cfg, arch)
+	if pkgURL == "" {
+		return "", fmt.Errorf("installMode=system: no systemPackage url configured for arch %q", arch)
+	}
+
+	fetcher, err := fetcherFor(pkgURL)
+	if err != nil {
+		return "", err
+	}
+	tmp := filepath.Join(os.TempDir(), fmt.Sprintf("awscli-pkg-%d%s", time.Now().UnixNano(), filepath.Ext(pkgURL)))
+	defer func() { _ = os.Remove(tmp) }()
+
+	digest, err := fetcher.Fetch(ctx, pkgURL, tmp)
+	if err != nil {
+		return "", fmt.Errorf("download system package: %w", err)
+	}
+	if want := systemPackageDigestFor(cfg, arch); want != "" {
+		if subtle.ConstantTimeCompare([]byte(strings.ToLower(digest)), []byte(strings.ToLower(want))) != 1 {
+			return "", fmt.Errorf("system package checksum mismatch: got %s want %s", digest, want)
+		}
+	}
+
+	args := pm.installArgs(tmp)
+	cmd := exec.CommandContext(ctx, elevate, append([]string{pm.name}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s install via %s: %w; output: %s", pm.name, elevate, err, strings.TrimSpace(string(out)))
+	}
+
+	path, err := exec.LookPath("aws")
+	if err != nil {
+		return "", fmt.Errorf("aws not found on PATH after system install: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// eksClusterNameRE matches the charset AWS itself enforces for EKS cluster
+// names, so a cluster name taken from user-typed chat text can never be
+// used to escape kubeconfigPath's directory via "../" or an absolute path.
+var eksClusterNameRE = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z_-]{0,99}$`)
+
+// listEKSClusters returns the names of EKS clusters visible to the current
+// credentials, for the `helper eks-kubeconfig` cluster picker.
+func listEKSClusters(ctx context.Context, ld, awsBin, libraryPath string, env []string) ([]string, error) {
+	args := []string{"eks", "list-clusters", "--query", "clusters", "--output", "text"}
+	out, err := runAWS(ctx, ld, awsBin, libraryPath, args, env)
+	if err != nil {
+		return nil, fmt.Errorf("list-clusters: %w; output: %s", err, strings.TrimSpace(string(out)))
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// kubeconfigPath returns the plugin-scoped path `update-kubeconfig` should
+// write cluster's kubeconfig to, creating the parent directory if needed.
+func kubeconfigPath(cluster string) (string, error) {
+	if !eksClusterNameRE.MatchString(cluster) {
+		return "", fmt.Errorf("invalid EKS cluster name %q", cluster)
+	}
+	depsRoot, err := depsDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(depsRoot, "kubeconfigs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, cluster+".yaml"), nil
+}
+
+// writeKubeconfig runs `aws eks update-kubeconfig` for cluster/region,
+// writing the result to its plugin-scoped kubeconfig path and returning
+// that path.
+func writeKubeconfig(ctx context.Context, ld, awsBin, libraryPath string, env []string, cluster, region string) (string, error) {
+	path, err := kubeconfigPath(cluster)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"eks", "update-kubeconfig", "--name", cluster, "--kubeconfig", path}
+	if region != "" {
+		args = append(args, "--region", region)
+	}
+	out, err := runAWS(ctx, ld, awsBin, libraryPath, args, env)
+	if err != nil {
+		return "", fmt.Errorf("update-kubeconfig: %w; output: %s", err, strings.TrimSpace(string(out)))
+	}
+	return path, nil
+}
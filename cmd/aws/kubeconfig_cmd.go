@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubeshop/botkube/pkg/api"
+	"github.com/kubeshop/botkube/pkg/api/executor"
+	"gopkg.in/yaml.v3"
+)
+
+// eksClusterInfo is the subset of `aws eks describe-cluster` output the
+// generated kubeconfig needs.
+type eksClusterInfo struct {
+	Endpoint string
+	CAData   string
+}
+
+func describeEKSCluster(ctx context.Context, ld, awsBin, libraryPath string, env []string, cluster, region string) (eksClusterInfo, error) {
+	args := []string{"eks", "describe-cluster", "--name", cluster, "--output", "json"}
+	if region != "" {
+		args = append(args, "--region", region)
+	}
+	out, err := runAWS(ctx, ld, awsBin, libraryPath, args, env)
+	if err != nil {
+		return eksClusterInfo{}, fmt.Errorf("describe-cluster: %w; output: %s", err, strings.TrimSpace(string(out)))
+	}
+	var payload struct {
+		Cluster struct {
+			Endpoint             string `json:"endpoint"`
+			CertificateAuthority struct {
+				Data string `json:"data"`
+			} `json:"certificateAuthority"`
+		} `json:"cluster"`
+	}
+	if err := json.Unmarshal(out, &payload); err != nil {
+		return eksClusterInfo{}, fmt.Errorf("parse describe-cluster output: %w", err)
+	}
+	return eksClusterInfo{Endpoint: payload.Cluster.Endpoint, CAData: payload.Cluster.CertificateAuthority.Data}, nil
+}
+
+type kubeconfigDoc struct {
+	APIVersion     string              `yaml:"apiVersion"`
+	Kind           string              `yaml:"kind"`
+	Clusters       []kubeconfigCluster `yaml:"clusters"`
+	Contexts       []kubeconfigContext `yaml:"contexts"`
+	CurrentContext string              `yaml:"current-context"`
+	Users          []kubeconfigUser    `yaml:"users"`
+}
+
+type kubeconfigCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server                   string `yaml:"server"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data,omitempty"`
+		CertificateAuthority     string `yaml:"certificate-authority,omitempty"`
+	} `yaml:"cluster"`
+}
+
+type kubeconfigContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster string `yaml:"cluster"`
+		User    string `yaml:"user"`
+	} `yaml:"context"`
+}
+
+type kubeconfigUser struct {
+	Name string `yaml:"name"`
+	User struct {
+		Exec struct {
+			APIVersion      string   `yaml:"apiVersion"`
+			Command         string   `yaml:"command"`
+			Args            []string `yaml:"args"`
+			InteractiveMode string   `yaml:"interactiveMode,omitempty"`
+		} `yaml:"exec"`
+	} `yaml:"user"`
+}
+
+// buildExecKubeconfig renders a kubeconfig whose users[].exec stanza calls
+// back into this same plugin binary (re-invoked in `__eks-token` mode) so
+// token refresh re-runs the same AssumeRole exchange the plugin already
+// knows how to cache, instead of requiring a separate aws-iam-authenticator
+// install on whatever machine runs kubectl.
+func buildExecKubeconfig(cluster, region, role, session string, info eksClusterInfo, caFile string) (string, error) {
+	selfPath, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	args := []string{"__eks-token", "--cluster", cluster}
+	if region != "" {
+		args = append(args, "--region", region)
+	}
+	if role != "" {
+		args = append(args, "--role", role)
+	}
+	if session != "" {
+		args = append(args, "--session", session)
+	}
+
+	doc := kubeconfigDoc{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		CurrentContext: cluster,
+	}
+	c := kubeconfigCluster{Name: cluster}
+	c.Cluster.Server = info.Endpoint
+	if caFile != "" {
+		c.Cluster.CertificateAuthority = caFile
+	} else {
+		c.Cluster.CertificateAuthorityData = info.CAData
+	}
+	doc.Clusters = []kubeconfigCluster{c}
+
+	ctxEntry := kubeconfigContext{Name: cluster}
+	ctxEntry.Context.Cluster = cluster
+	ctxEntry.Context.User = cluster
+	doc.Contexts = []kubeconfigContext{ctxEntry}
+
+	u := kubeconfigUser{Name: cluster}
+	u.User.Exec.APIVersion = "client.authentication.k8s.io/v1beta1"
+	u.User.Exec.Command = selfPath
+	u.User.Exec.Args = args
+	u.User.Exec.InteractiveMode = "Never"
+	doc.Users = []kubeconfigUser{u}
+
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// kubeconfigOutputPath resolves a user-supplied --file/--ca-file value to a
+// path under depsDir()/kubeconfigs, the same directory the chunk1-2
+// `helper eks-kubeconfig` command writes to. name is taken straight from
+// chat text, so it's rejected outright if it's absolute or would escape
+// that directory via "../" traversal rather than ever being passed to
+// os.WriteFile as-is.
+func kubeconfigOutputPath(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("path %q must be relative", name)
+	}
+	depsRoot, err := depsDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(depsRoot, "kubeconfigs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return safeJoin(dir, name)
+}
+
+// parseFlagPairs turns `--key value --key2 value2` style fields into a map.
+func parseFlagPairs(fields []string) map[string]string {
+	out := map[string]string{}
+	for i := 0; i < len(fields)-1; i++ {
+		if strings.HasPrefix(fields[i], "--") {
+			out[strings.TrimPrefix(fields[i], "--")] = fields[i+1]
+		}
+	}
+	return out
+}
+
+// handleKubeconfigSubcommand implements `aws kubeconfig --cluster <name>
+// --region <r> [--role <arn>] [--session <name>] [--ca-file <path>]
+// [--file <path>]`, assuming a role via STS when --role is given (falling
+// back to the profile's configured AssumeRole otherwise) and writing (or
+// returning inline) a ready-to-use kubeconfig. --file and --ca-file are
+// resolved via kubeconfigOutputPath, so they're always relative paths
+// confined to depsDir()/kubeconfigs, never a path the chat user can point
+// anywhere else the plugin process can reach.
+func (e *Executor) handleKubeconfigSubcommand(ctx context.Context, cfg Config, assumeRole AssumeRoleConfig, rest, userHint string) (executor.ExecuteOutput, error) {
+	flags := parseFlagPairs(strings.Fields(rest))
+	cluster := flags["cluster"]
+	if cluster == "" {
+		return msg("usage: aws kubeconfig --cluster <name> --region <r> [--role <arn>] [--session <name>] [--ca-file <path>] [--file <path>]"), nil
+	}
+	region := flags["region"]
+	if region == "" {
+		region = cfg.DefaultRegion
+	}
+
+	ar := assumeRole
+	if role := flags["role"]; role != "" {
+		ar = AssumeRoleConfig{RoleArn: role, SessionName: flags["session"]}
+	}
+	roleEnv, err := assumeRoleEnv(ctx, ar, userHint)
+	if err != nil {
+		return msg("failed to assume role: " + err.Error()), nil
+	}
+
+	awsBin, glibcDir, distDir, err := prepareAws(ctx, cfg)
+	if err != nil {
+		return msg("failed to prepare aws cli: " + err.Error()), nil
+	}
+	ld := resolveLoaderPath(glibcDir)
+	libraryPath := buildLDPath(glibcDir, distDir)
+	env := append(buildEnv(cfg, libraryPath), roleEnv...)
+
+	info, derr := describeEKSCluster(ctx, ld, awsBin, libraryPath, env, cluster, region)
+	if derr != nil {
+		return msg("failed to describe cluster: " + derr.Error()), nil
+	}
+
+	caFile := flags["ca-file"]
+	if caFile != "" {
+		resolved, perr := kubeconfigOutputPath(caFile)
+		if perr != nil {
+			return msg("invalid --ca-file: " + perr.Error()), nil
+		}
+		caData, decErr := base64.StdEncoding.DecodeString(info.CAData)
+		if decErr != nil {
+			return msg("failed to decode cluster CA: " + decErr.Error()), nil
+		}
+		if err := os.WriteFile(resolved, caData, 0o644); err != nil {
+			return msg("failed to write ca-file: " + err.Error()), nil
+		}
+		caFile = resolved
+	}
+
+	kubeconfig, kerr := buildExecKubeconfig(cluster, region, ar.RoleArn, ar.SessionName, info, caFile)
+	if kerr != nil {
+		return msg("failed to render kubeconfig: " + kerr.Error()), nil
+	}
+
+	if file := flags["file"]; file != "" {
+		resolved, perr := kubeconfigOutputPath(file)
+		if perr != nil {
+			return msg("invalid --file: " + perr.Error()), nil
+		}
+		if err := os.WriteFile(resolved, []byte(kubeconfig), 0o644); err != nil {
+			return msg("failed to write kubeconfig: " + err.Error()), nil
+		}
+		return msg("kubeconfig written to " + resolved), nil
+	}
+	return executor.ExecuteOutput{Message: api.NewCodeBlockMessage(kubeconfig, true)}, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OutputConfig governs how command output that is too big for a chat
+// message gets truncated and how the full copy is retained on disk.
+type OutputConfig struct {
+	MaxInlineBytes   int    `yaml:"maxInlineBytes,omitempty"`
+	TruncateStrategy string `yaml:"truncateStrategy,omitempty"` // head|tail|middle
+	MetaMaxBytes     int    `yaml:"metaMaxBytes,omitempty"`
+}
+
+func (c OutputConfig) withDefaults() OutputConfig {
+	if c.MaxInlineBytes <= 0 {
+		c.MaxInlineBytes = 30 * 1024
+	}
+	if c.TruncateStrategy == "" {
+		c.TruncateStrategy = "tail"
+	}
+	if c.MetaMaxBytes <= 0 {
+		c.MetaMaxBytes = 4 * 1024
+	}
+	return c
+}
+
+// newRunID returns a lexicographically time-sortable id: the current
+// millisecond timestamp followed by random hex, fixed-width so string
+// ordering matches chronological ordering.
+func newRunID() string {
+	b := make([]byte, 5)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%013x%s", time.Now().UnixMilli(), hex.EncodeToString(b))
+}
+
+// truncateForChat trims s to cfg.MaxInlineBytes according to
+// cfg.TruncateStrategy, reporting whether it had to cut anything.
+func truncateForChat(s string, cfg OutputConfig) (out string, truncated bool) {
+	if len(s) <= cfg.MaxInlineBytes {
+		return s, false
+	}
+	switch cfg.TruncateStrategy {
+	case "head":
+		return s[:cfg.MaxInlineBytes], true
+	case "middle":
+		half := cfg.MaxInlineBytes / 2
+		return s[:half] + "\n... (truncated) ...\n" + s[len(s)-half:], true
+	default: // "tail"
+		return s[len(s)-cfg.MaxInlineBytes:], true
+	}
+}
+
+// persistRunOutput writes full to depsDir()/runs/<id>.txt so a truncated
+// inline copy can be paged through later via `helper show`/`helper
+// download`, and returns the id plus a size+hash footer line.
+func persistRunOutput(full string) (id, footer string, err error) {
+	depsRoot, err := depsDir()
+	if err != nil {
+		return "", "", err
+	}
+	dir := filepath.Join(depsRoot, "runs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", err
+	}
+	id = newRunID()
+	path := filepath.Join(dir, id+".txt")
+	if err := os.WriteFile(path, []byte(full), 0o644); err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(full))
+	footer = fmt.Sprintf(
+		"(truncated; full output is %d bytes, sha256 %s — run `aws helper show %s` or `aws helper download %s`)",
+		len(full), hex.EncodeToString(sum[:]), id, id,
+	)
+	return id, footer, nil
+}
+
+func runOutputPath(id string) (string, error) {
+	depsRoot, err := depsDir()
+	if err != nil {
+		return "", err
+	}
+	return safeJoin(filepath.Join(depsRoot, "runs"), id+".txt")
+}
+
+const runShowPageSize = 200
+
+// fetchRunPage slices the cached output for id by lines, runShowPageSize
+// lines per page.
+func fetchRunPage(id string, page int) (string, error) {
+	path, err := runOutputPath(id)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("no cached output for %q: %w", id, err)
+	}
+	lines := strings.Split(string(b), "\n")
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * runShowPageSize
+	if start >= len(lines) {
+		return "", fmt.Errorf("page %d is past the end (%d lines total)", page, len(lines))
+	}
+	end := start + runShowPageSize
+	if end > len(lines) {
+		end = len(lines)
+	}
+	body := strings.Join(lines[start:end], "\n")
+	return fmt.Sprintf("%s\n-- page %d (lines %d-%d of %d) --", body, page, start+1, end, len(lines)), nil
+}
+
+// fetchRunFull returns the entire cached output for id, for `helper
+// download`.
+func fetchRunFull(id string) (string, error) {
+	path, err := runOutputPath(id)
+	if err != nil {
+		return "", err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("no cached output for %q: %w", id, err)
+	}
+	return string(b), nil
+}
+
+func parsePageFlag(fields []string) int {
+	page := 1
+	for i, f := range fields {
+		if f == "--page" && i+1 < len(fields) {
+			if n, err := strconv.Atoi(fields[i+1]); err == nil {
+				page = n
+			}
+		}
+	}
+	return page
+}
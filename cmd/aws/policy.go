@@ -0,0 +1,254 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PolicyRule is one ordered entry in Config.Policy. Rules are evaluated
+// top-to-bottom and the first match wins; an implicit deny applies if
+// nothing matches.
+type PolicyRule struct {
+	Match           string   `yaml:"match,omitempty"`
+	Classify        string   `yaml:"classify,omitempty"` // read|write|destructive
+	Effect          string   `yaml:"effect"`              // allow|deny
+	Reason          string   `yaml:"reason,omitempty"`
+	RequireProfile  []string `yaml:"requireProfile,omitempty"`
+	RequireApproval bool     `yaml:"requireApproval,omitempty"`
+}
+
+const (
+	effectAllow = "allow"
+	effectDeny  = "deny"
+
+	classifyRead        = "read"
+	classifyWrite       = "write"
+	classifyDestructive = "destructive"
+)
+
+var (
+	readVerbs        = []string{"describe", "list", "get", "head"}
+	writeVerbs       = []string{"create", "update", "put", "modify", "start", "attach", "associate"}
+	destructiveVerbs = []string{"delete", "terminate", "reboot", "stop", "detach", "disassociate", "revoke"}
+)
+
+// classifyCommand tags an `aws <service> <verb> ...` invocation as read,
+// write, or destructive based on its verb, mirroring the read/write/
+// destructive split AWS itself uses for IAM action naming.
+func classifyCommand(cmdLine string) string {
+	fields := strings.Fields(cmdLine)
+	if len(fields) < 2 {
+		return ""
+	}
+	verb := fields[1]
+	hasAny := func(prefixes []string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(verb, p) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case hasAny(destructiveVerbs):
+		return classifyDestructive
+	case hasAny(writeVerbs):
+		return classifyWrite
+	case hasAny(readVerbs):
+		return classifyRead
+	default:
+		return ""
+	}
+}
+
+// compileMatcher turns a PolicyRule.Match pattern into a predicate. Patterns
+// prefixed with "re:" are regular expressions; everything else is a glob
+// over whitespace-separated tokens where "*" matches within a token and
+// "**" matches one or more tokens (not zero).
+func compileMatcher(pattern string) (func(cmdLine string) bool, error) {
+	if rx, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile(rx)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern %q: %w", rx, err)
+		}
+		return re.MatchString, nil
+	}
+
+	tokens := strings.Fields(pattern)
+	var b strings.Builder
+	b.WriteString("^")
+	for i, t := range tokens {
+		if i > 0 {
+			b.WriteString(`\s+`)
+		}
+		switch t {
+		case "**":
+			b.WriteString(`.*`)
+		default:
+			escaped := regexp.QuoteMeta(t)
+			escaped = strings.ReplaceAll(escaped, `\*`, `[^\s]*`)
+			b.WriteString(escaped)
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	return re.MatchString, nil
+}
+
+// policyDecision is the outcome of evaluating Config.Policy against a
+// command.
+type policyDecision struct {
+	allowed         bool
+	reason          string
+	requireApproval bool
+}
+
+// evaluatePolicy walks rules top-to-bottom and returns the first match; when
+// nothing matches, the command is denied (implicit deny).
+func evaluatePolicy(rules []PolicyRule, cmdLine, profileName string) (policyDecision, error) {
+	class := classifyCommand(cmdLine)
+	for _, r := range rules {
+		matched := false
+		switch {
+		case r.Classify != "":
+			matched = r.Classify == class
+		case r.Match != "":
+			matcher, err := compileMatcher(r.Match)
+			if err != nil {
+				return policyDecision{}, err
+			}
+			matched = matcher(cmdLine)
+		default:
+			matched = true
+		}
+		if !matched {
+			continue
+		}
+		if len(r.RequireProfile) > 0 && !containsString(r.RequireProfile, profileName) {
+			continue
+		}
+		return policyDecision{
+			allowed:         r.Effect == effectAllow,
+			reason:          r.Reason,
+			requireApproval: r.RequireApproval && r.Effect == effectAllow,
+		}, nil
+	}
+	return policyDecision{allowed: false, reason: "no policy rule matched (implicit deny)"}, nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// approvalEntry is a pending command waiting for an `approvers:` member to
+// click Approve before it is re-run.
+type approvalEntry struct {
+	cmdLine string
+	issued  time.Time
+}
+
+const approvalTokenTTL = 15 * time.Minute
+
+var (
+	approvalMu    sync.Mutex
+	approvalByTok = map[string]approvalEntry{}
+)
+
+func newApprovalToken(cmdLine string) string {
+	b := make([]byte, 9)
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+	approvalMu.Lock()
+	approvalByTok[token] = approvalEntry{cmdLine: cmdLine, issued: time.Now()}
+	approvalMu.Unlock()
+	return token
+}
+
+// isApprover reports whether mention or displayName (the clicking user's
+// identity, per ExecuteInput.Context.Message.User) matches an entry in
+// approvers, case-insensitively and ignoring a leading "@". An empty
+// approvers list means no group is configured, so there is nothing to
+// enforce and every clicker passes.
+func isApprover(approvers []string, mention, displayName string) bool {
+	if len(approvers) == 0 {
+		return true
+	}
+	norm := func(s string) string { return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "@") }
+	mention, displayName = norm(mention), norm(displayName)
+	for _, a := range approvers {
+		na := norm(a)
+		if na == "" {
+			continue
+		}
+		if (mention != "" && na == mention) || (displayName != "" && na == displayName) {
+			return true
+		}
+	}
+	return false
+}
+
+// approverIdentity picks whichever of mention/displayName is non-empty, for
+// use in a denial message.
+func approverIdentity(mention, displayName string) string {
+	if mention != "" {
+		return mention
+	}
+	if displayName != "" {
+		return displayName
+	}
+	return "<unknown user>"
+}
+
+// consumeApprovalToken resolves token to the command it was issued for. A
+// token is single-use and expires after approvalTokenTTL.
+//
+// mention/displayName are the clicking user's identity (ExecuteInput.
+// Context.Message.User), checked against approvers via isApprover. This is
+// a partial check, not full `approvers:` group enforcement: it's a plain
+// case-insensitive string match against the configured list, not a lookup
+// against the chat platform's actual group/role membership, and if a
+// platform's button-click callback doesn't relay Message.User at all (this
+// plugin has no way to confirm that in advance for every botkube frontend),
+// mention and displayName both arrive empty and, when approvers is
+// non-empty, isApprover denies every click outright rather than silently
+// letting it through; with an empty approvers list every click is
+// accepted. Operators should
+// not treat this as equivalent to real group enforcement — see the
+// "Approval required" message and help.go for the same caveat surfaced to
+// chat users.
+func consumeApprovalToken(token string, approvers []string, mention, displayName string) (string, error) {
+	approvalMu.Lock()
+	e, ok := approvalByTok[token]
+	if !ok {
+		approvalMu.Unlock()
+		return "", fmt.Errorf("unknown or already-used approval token")
+	}
+	if time.Since(e.issued) > approvalTokenTTL {
+		delete(approvalByTok, token)
+		approvalMu.Unlock()
+		return "", fmt.Errorf("approval token expired")
+	}
+	approvalMu.Unlock()
+
+	if !isApprover(approvers, mention, displayName) {
+		return "", fmt.Errorf("approval denied: %s is not one of the configured approvers (%s)", approverIdentity(mention, displayName), strings.Join(approvers, ", "))
+	}
+
+	approvalMu.Lock()
+	delete(approvalByTok, token)
+	approvalMu.Unlock()
+	return e.cmdLine, nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamingConfig bounds how long-running `aws` invocations (logs tail, s3
+// sync, cloudformation deploy, ...) are allowed to run and how much of their
+// output is kept. FlushInterval and MaxBytesPerChunk only mark chunk
+// boundaries internally; output is still returned to chat as one message.
+type StreamingConfig struct {
+	FlushInterval    time.Duration `yaml:"flushInterval,omitempty"`
+	MaxBytesPerChunk int           `yaml:"maxBytesPerChunk,omitempty"`
+	MaxTotalBytes    int64         `yaml:"maxTotalBytes,omitempty"`
+	HardTimeout      time.Duration `yaml:"hardTimeout,omitempty"`
+}
+
+const (
+	defaultFlushInterval    = 800 * time.Millisecond
+	defaultMaxBytesPerChunk = 3 << 10 // 3 KiB
+	defaultMaxTotalBytes    = 8 << 20 // 8 MiB
+	defaultHardTimeout      = 5 * time.Minute
+)
+
+func (s StreamingConfig) withDefaults() StreamingConfig {
+	if s.FlushInterval <= 0 {
+		s.FlushInterval = defaultFlushInterval
+	}
+	if s.MaxBytesPerChunk <= 0 {
+		s.MaxBytesPerChunk = defaultMaxBytesPerChunk
+	}
+	if s.MaxTotalBytes <= 0 {
+		s.MaxTotalBytes = defaultMaxTotalBytes
+	}
+	if s.HardTimeout <= 0 {
+		s.HardTimeout = defaultHardTimeout
+	}
+	return s
+}
+
+// runningCmd tracks an in-flight *exec.Cmd so a later `aws cancel <id>`
+// invocation (handled by a concurrent Execute call) can stop it.
+type runningCmd struct {
+	cmd     *exec.Cmd
+	cancel  context.CancelFunc
+	started time.Time
+}
+
+var (
+	runningMu   sync.Mutex
+	runningByID = map[string]*runningCmd{}
+)
+
+func newCommandID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func registerRunning(id string, rc *runningCmd) {
+	runningMu.Lock()
+	runningByID[id] = rc
+	runningMu.Unlock()
+}
+
+func unregisterRunning(id string) {
+	runningMu.Lock()
+	delete(runningByID, id)
+	runningMu.Unlock()
+}
+
+// cancelRunning signals the process registered under id to stop: SIGINT
+// first, then SIGKILL after a short grace period if it is still alive.
+func cancelRunning(id string) error {
+	runningMu.Lock()
+	rc, ok := runningByID[id]
+	runningMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no running command with id %q", id)
+	}
+	if rc.cmd.Process == nil {
+		return fmt.Errorf("command %q has no process", id)
+	}
+	_ = rc.cmd.Process.Signal(os.Interrupt)
+	go func() {
+		time.Sleep(5 * time.Second)
+		runningMu.Lock()
+		_, stillRunning := runningByID[id]
+		runningMu.Unlock()
+		if stillRunning {
+			_ = rc.cmd.Process.Kill()
+		}
+	}()
+	return nil
+}
+
+// streamResult is the outcome of a streamed aws invocation.
+type streamResult struct {
+	output     string
+	truncated  bool
+	spillPath  string
+	totalBytes int64
+}
+
+// runAWSStreaming runs the aws invocation described by ld/awsBin/libraryPath/
+// args/env, reading its combined stdout+stderr by line into a single result;
+// nothing is sent before the command exits, and flushInterval/
+// maxBytesPerChunk do not split the output. It enforces cfg.HardTimeout and,
+// once cfg.MaxTotalBytes is exceeded, spills the remainder to a temp file
+// instead of holding it in memory. It blocks on cmd.Wait() before returning,
+// so by the time the caller learns the returned id the command has already
+// finished (or hit HardTimeout) and unregisterRunning has already run; id is
+// only useful for a cancelRunning call racing it from outside this call.
+func runAWSStreaming(ctx context.Context, ld, awsBin, libraryPath string, args, env []string, cfg StreamingConfig) (id string, res streamResult, _ error) {
+	cfg = cfg.withDefaults()
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.HardTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if ld != "" {
+		loaderArgs := append([]string{"--library-path", libraryPath, awsBin}, args...)
+		cmd = exec.CommandContext(runCtx, ld, loaderArgs...)
+	} else {
+		cmd = exec.CommandContext(runCtx, awsBin, args...)
+	}
+	cmd.Env = env
+
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return "", res, err
+	}
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	if err := cmd.Start(); err != nil {
+		_ = pw.Close()
+		_ = pr.Close()
+		return "", res, err
+	}
+	id = newCommandID()
+	registerRunning(id, &runningCmd{cmd: cmd, cancel: cancel, started: time.Now()})
+	defer unregisterRunning(id)
+
+	var (
+		buf        strings.Builder
+		lastFlush  = time.Now()
+		spillFile  *os.File
+		spillBytes int64
+	)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		for scanner.Scan() {
+			line := scanner.Text()
+			res.totalBytes += int64(len(line)) + 1
+
+			switch {
+			case res.totalBytes > cfg.MaxTotalBytes:
+				res.truncated = true
+				if spillFile == nil {
+					spillFile, err = os.CreateTemp("", "aws-output-*.txt")
+					if err == nil {
+						res.spillPath = spillFile.Name()
+						_, _ = spillFile.WriteString(buf.String())
+					}
+				}
+				if spillFile != nil {
+					n, _ := spillFile.WriteString(line + "\n")
+					spillBytes += int64(n)
+				}
+			default:
+				buf.WriteString(line)
+				buf.WriteByte('\n')
+			}
+
+			// A chunk boundary (time- or size-based) is where a real
+			// streaming transport would flush a follow-up ExecuteOutput
+			// message; this SDK only returns one message per Execute call,
+			// so a boundary only resets lastFlush and buf keeps growing.
+			if buf.Len() >= cfg.MaxBytesPerChunk || time.Since(lastFlush) >= cfg.FlushInterval {
+				lastFlush = time.Now()
+			}
+		}
+	}()
+
+	waitErr := cmd.Wait()
+	_ = pw.Close()
+	<-done
+	_ = pr.Close()
+	if spillFile != nil {
+		_ = spillFile.Close()
+	}
+
+	res.output = strings.TrimSpace(buf.String())
+	if waitErr != nil {
+		return id, res, waitErr
+	}
+	return id, res, nil
+}
+
+// sizeSummary renders a short human footer describing truncated output.
+func sizeSummary(res streamResult) string {
+	return fmt.Sprintf("(output truncated; %d bytes in total, full output saved to %s)", res.totalBytes, res.spillPath)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubeshop/botkube/pkg/api"
+)
+
+// TemplateParam describes one parameter a form-driven template collects
+// before it is allowed to run, along with the aws CLI query whose results
+// become its option buttons.
+type TemplateParam struct {
+	Name        string `yaml:"name"`
+	Flag        string `yaml:"flag"`
+	SourceQuery string `yaml:"sourceQuery,omitempty"`
+}
+
+// Template declares a parameterized aws invocation that should be filled in
+// via an interactive form instead of hand-edited placeholders.
+type Template struct {
+	Pattern string          `yaml:"pattern"`
+	Params  []TemplateParam `yaml:"params"`
+}
+
+// builtinTemplates ships a sane default set so operators don't have to
+// redeclare the common destructive-but-parameterized operations.
+var builtinTemplates = map[string]Template{
+	"ec2-reboot-instances": {
+		Pattern: "ec2 reboot-instances",
+		Params: []TemplateParam{
+			{Name: "instance-ids", Flag: "--instance-ids", SourceQuery: "ec2 describe-instances --query Reservations[].Instances[].InstanceId --output text"},
+		},
+	},
+	"asg-start-instance-refresh": {
+		Pattern: "autoscaling start-instance-refresh",
+		Params: []TemplateParam{
+			{Name: "auto-scaling-group-name", Flag: "--auto-scaling-group-name", SourceQuery: "autoscaling describe-auto-scaling-groups --query AutoScalingGroups[].AutoScalingGroupName --output text"},
+		},
+	},
+	"eks-update-nodegroup-version": {
+		Pattern: "eks update-nodegroup-version",
+		Params: []TemplateParam{
+			{Name: "cluster-name", Flag: "--cluster-name", SourceQuery: "eks list-clusters --query clusters --output text"},
+			{Name: "nodegroup-name", Flag: "--nodegroup-name", SourceQuery: "eks list-nodegroups --query nodegroups --output text"},
+		},
+	},
+	"rds-reboot-db-instance": {
+		Pattern: "rds reboot-db-instance",
+		Params: []TemplateParam{
+			{Name: "db-instance-identifier", Flag: "--db-instance-identifier", SourceQuery: "rds describe-db-instances --query DBInstances[].DBInstanceIdentifier --output text"},
+		},
+	},
+}
+
+const templateOptionsCacheTTL = 5 * time.Minute
+
+type templateOptionsEntry struct {
+	options []string
+	fetched time.Time
+}
+
+var (
+	templateOptionsMu    sync.Mutex
+	templateOptionsCache = map[string]templateOptionsEntry{}
+)
+
+// allTemplates merges the built-ins with any operator-declared overrides,
+// letting Config.Templates replace or add entries by id.
+func allTemplates(cfg Config) map[string]Template {
+	out := make(map[string]Template, len(builtinTemplates)+len(cfg.Templates))
+	for id, t := range builtinTemplates {
+		out[id] = t
+	}
+	for id, t := range cfg.Templates {
+		out[id] = t
+	}
+	return out
+}
+
+// matchTemplate finds the template whose pattern is a prefix of cmdLine.
+func matchTemplate(cfg Config, cmdLine string) (id string, tpl Template, ok bool) {
+	for id, t := range allTemplates(cfg) {
+		if strings.HasPrefix(cmdLine, t.Pattern) {
+			return id, t, true
+		}
+	}
+	return "", Template{}, false
+}
+
+// parseFormSubmit recognizes `_form_submit <template-id> k=v k2=v2 ...` and
+// returns the template id and the submitted key=value parameters.
+func parseFormSubmit(cmdLine string) (id string, params map[string]string, ok bool) {
+	rest, ok := strings.CutPrefix(cmdLine, "_form_submit ")
+	if !ok {
+		return "", nil, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	params = make(map[string]string, len(fields)-1)
+	for _, f := range fields[1:] {
+		k, v, found := strings.Cut(f, "=")
+		if !found {
+			continue
+		}
+		params[k] = v
+	}
+	return fields[0], params, true
+}
+
+// nextTemplateParam returns the first of tpl.Params not yet present in
+// selected, so a multi-parameter template's form can be driven one
+// parameter at a time instead of requiring every parameter in a single
+// message. ok is false once every required parameter has been picked.
+func nextTemplateParam(tpl Template, selected map[string]string) (p TemplateParam, ok bool) {
+	for _, p := range tpl.Params {
+		if v, set := selected[p.Name]; !set || v == "" {
+			return p, true
+		}
+	}
+	return TemplateParam{}, false
+}
+
+// formSubmitArgs renders selected plus the newly picked name=value as the
+// `k=v k2=v2 ...` argument list of a `_form_submit` command, so each
+// button carries forward every parameter already chosen earlier in the
+// form rather than only the one the user just clicked. Keys are sorted so
+// the generated command is deterministic.
+func formSubmitArgs(selected map[string]string, name, value string) string {
+	args := make([]string, 0, len(selected)+1)
+	for k, v := range selected {
+		args = append(args, k+"="+v)
+	}
+	args = append(args, name+"="+value)
+	sort.Strings(args)
+	return strings.Join(args, " ")
+}
+
+// renderTemplate turns a submitted form into the final `aws ...` invocation.
+func renderTemplate(cfg Config, id string, params map[string]string) (string, error) {
+	tpl, ok := allTemplates(cfg)[id]
+	if !ok {
+		return "", fmt.Errorf("unknown template %q", id)
+	}
+	cmdLine := tpl.Pattern
+	for _, p := range tpl.Params {
+		v, ok := params[p.Name]
+		if !ok || v == "" {
+			return "", fmt.Errorf("template %q: missing required parameter %q", id, p.Name)
+		}
+		cmdLine += " " + p.Flag + " " + v
+	}
+	return cmdLine, nil
+}
+
+// templateFormEnv prepares the aws binary and environment (including an
+// assumed role, if configured) that both populating a template form's
+// dropdowns and executing the rendered command need.
+func templateFormEnv(ctx context.Context, cfg Config, assumeRole AssumeRoleConfig, userHint string) (ld, awsBin, libraryPath string, env []string, err error) {
+	awsBin, glibcDir, distDir, err := prepareAws(ctx, cfg)
+	if err != nil {
+		return "", "", "", nil, fmt.Errorf("failed to prepare aws cli: %w", err)
+	}
+	ld = resolveLoaderPath(glibcDir)
+	libraryPath = buildLDPath(glibcDir, distDir)
+	env = buildEnv(cfg, libraryPath)
+	roleEnv, rerr := assumeRoleEnv(ctx, assumeRole, userHint)
+	if rerr != nil {
+		return "", "", "", nil, fmt.Errorf("failed to assume role: %w", rerr)
+	}
+	env = append(env, roleEnv...)
+	return ld, awsBin, libraryPath, env, nil
+}
+
+// fetchTemplateOptions runs param.SourceQuery through the aws CLI and caches
+// the resulting options per (region, query) for templateOptionsCacheTTL.
+func fetchTemplateOptions(ctx context.Context, ld, awsBin, libraryPath string, env []string, region, query string) ([]string, error) {
+	key := region + "|" + query
+	templateOptionsMu.Lock()
+	if e, ok := templateOptionsCache[key]; ok && time.Since(e.fetched) < templateOptionsCacheTTL {
+		templateOptionsMu.Unlock()
+		return e.options, nil
+	}
+	templateOptionsMu.Unlock()
+
+	args := strings.Fields(query)
+	out, err := runAWS(ctx, ld, awsBin, libraryPath, args, env)
+	if err != nil {
+		return nil, fmt.Errorf("source query %q: %w; output: %s", query, err, strings.TrimSpace(string(out)))
+	}
+	options := strings.Fields(string(out))
+
+	templateOptionsMu.Lock()
+	templateOptionsCache[key] = templateOptionsEntry{options: options, fetched: time.Now()}
+	templateOptionsMu.Unlock()
+	return options, nil
+}
+
+// renderTemplateForm builds the interactive button section for the next
+// parameter of tpl that selected hasn't picked yet, with one button per
+// option from the parameter's live AWS listing (a button list, not a
+// select dropdown). Every button's
+// generated command carries forward selected plus the option just
+// clicked (see formSubmitArgs), so a multi-parameter template is
+// completed by walking through its params one form message at a time
+// rather than requiring every parameter to be set in a single message.
+// Callers must only invoke this when nextTemplateParam(tpl, selected)
+// reports a parameter remaining.
+func renderTemplateForm(ctx context.Context, cfg Config, id string, tpl Template, ld, awsBin, libraryPath string, env []string, selected map[string]string) (api.Message, error) {
+	next, ok := nextTemplateParam(tpl, selected)
+	if !ok {
+		return api.Message{}, fmt.Errorf("template %q: all parameters already selected", id)
+	}
+
+	var options []string
+	if next.SourceQuery != "" {
+		opts, err := fetchTemplateOptions(ctx, ld, awsBin, libraryPath, env, cfg.DefaultRegion, next.SourceQuery)
+		if err != nil {
+			return api.Message{}, fmt.Errorf("populate %q: %w", next.Name, err)
+		}
+		options = opts
+	}
+	items := make([]api.Button, 0, len(options))
+	builder := api.NewMessageButtonBuilder()
+	for _, o := range options {
+		items = append(items, builder.ForCommandWithDescCmd(o, fmt.Sprintf("aws _form_submit %s %s", id, formSubmitArgs(selected, next.Name, o))))
+	}
+
+	picked := make([]string, 0, len(selected))
+	for _, p := range tpl.Params {
+		if v, set := selected[p.Name]; set {
+			picked = append(picked, p.Name+"="+v)
+		}
+	}
+	desc := "Pick a value for " + next.Name + "."
+	if len(picked) > 0 {
+		desc = strings.Join(picked, " ") + " -- now pick a value for " + next.Name + "."
+	}
+	header := api.Section{
+		Base: api.Base{
+			Header:      "Pick parameters for " + tpl.Pattern,
+			Description: desc,
+		},
+	}
+	return api.Message{
+		Sections: []api.Section{header, {Base: api.Base{Header: next.Name}, Buttons: items}},
+	}, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// TestEKSUpdateNodegroupVersionFormEndToEnd drives the built-in two-parameter
+// eks-update-nodegroup-version template through the same sequence Execute
+// does: render the form for the first missing parameter, submit it, render
+// the form for the second, submit it, and confirm the fully-selected
+// template renders to the expected aws invocation instead of erroring.
+func TestEKSUpdateNodegroupVersionFormEndToEnd(t *testing.T) {
+	const id = "eks-update-nodegroup-version"
+	tpl, ok := builtinTemplates[id]
+	if !ok {
+		t.Fatalf("builtin template %q missing", id)
+	}
+	// Swap in a SourceQuery-free copy so the form can be rendered without
+	// shelling out to the aws CLI; the override mechanism (Config.Templates)
+	// is the same one operators use to customize builtins.
+	noQuery := tpl
+	noQuery.Params = make([]TemplateParam, len(tpl.Params))
+	for i, p := range tpl.Params {
+		noQuery.Params[i] = TemplateParam{Name: p.Name, Flag: p.Flag}
+	}
+	cfg := Config{Templates: map[string]Template{id: noQuery}}
+	ctx := context.Background()
+
+	selected := map[string]string{}
+	next, ok := nextTemplateParam(noQuery, selected)
+	if !ok || next.Name != "cluster-name" {
+		t.Fatalf("expected cluster-name first, got %+v ok=%v", next, ok)
+	}
+	form, err := renderTemplateForm(ctx, cfg, id, noQuery, "", "", "", nil, selected)
+	if err != nil {
+		t.Fatalf("renderTemplateForm (1st param): %v", err)
+	}
+	if len(form.Sections) != 2 || form.Sections[1].Base.Header != "cluster-name" {
+		t.Fatalf("expected a cluster-name section, got %+v", form.Sections)
+	}
+
+	submit1 := "_form_submit " + id + " " + formSubmitArgs(selected, "cluster-name", "prod")
+	tplID, params, ok := parseFormSubmit(submit1)
+	if !ok || tplID != id {
+		t.Fatalf("parseFormSubmit(1st submit) = %q, %v, %v", tplID, params, ok)
+	}
+
+	next, ok = nextTemplateParam(noQuery, params)
+	if !ok || next.Name != "nodegroup-name" {
+		t.Fatalf("expected nodegroup-name next, got %+v ok=%v", next, ok)
+	}
+	form, err = renderTemplateForm(ctx, cfg, id, noQuery, "", "", "", nil, params)
+	if err != nil {
+		t.Fatalf("renderTemplateForm (2nd param): %v", err)
+	}
+	if len(form.Sections) != 2 || form.Sections[1].Base.Header != "nodegroup-name" {
+		t.Fatalf("expected a nodegroup-name section, got %+v", form.Sections)
+	}
+
+	submit2 := "_form_submit " + id + " " + formSubmitArgs(params, "nodegroup-name", "ng-1")
+	tplID, params, ok = parseFormSubmit(submit2)
+	if !ok || tplID != id {
+		t.Fatalf("parseFormSubmit(2nd submit) = %q, %v, %v", tplID, params, ok)
+	}
+
+	if _, incomplete := nextTemplateParam(noQuery, params); incomplete {
+		t.Fatalf("expected all parameters selected after 2nd submit, got %+v", params)
+	}
+
+	rendered, err := renderTemplate(cfg, id, params)
+	if err != nil {
+		t.Fatalf("renderTemplate: %v", err)
+	}
+	const want = "eks update-nodegroup-version --cluster-name prod --nodegroup-name ng-1"
+	if rendered != want {
+		t.Fatalf("rendered = %q, want %q", rendered, want)
+	}
+}
+
+// TestRenderTemplateFormSingleParamRendersOneSection confirms a
+// single-parameter template (the case that worked before this fix) still
+// produces the header section plus exactly one parameter button section.
+func TestRenderTemplateFormSingleParamRendersOneSection(t *testing.T) {
+	const id = "rds-reboot-db-instance"
+	tpl := builtinTemplates[id]
+	tpl.Params = []TemplateParam{{Name: tpl.Params[0].Name, Flag: tpl.Params[0].Flag}}
+	cfg := Config{Templates: map[string]Template{id: tpl}}
+
+	form, err := renderTemplateForm(context.Background(), cfg, id, tpl, "", "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("renderTemplateForm: %v", err)
+	}
+	if len(form.Sections) != 2 {
+		t.Fatalf("expected header + 1 param section, got %d", len(form.Sections))
+	}
+}
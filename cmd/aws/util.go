@@ -12,6 +12,7 @@ import (
 
 	"github.com/kubeshop/botkube/pkg/api"
 	"github.com/kubeshop/botkube/pkg/api/executor"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -36,6 +37,13 @@ func depsDir() (string, error) {
 }
 
 func httpGetToFile(ctx context.Context, url, dst string) error {
+	return httpGetToWriter(ctx, url, dst, io.Discard)
+}
+
+// httpGetToWriter downloads url to dst, simultaneously streaming the bytes
+// through extra (e.g. a hash.Hash) via io.MultiWriter so callers can verify
+// the download without a second pass over the file.
+func httpGetToWriter(ctx context.Context, url, dst string, extra io.Writer) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
 	if err != nil {
 		return err
@@ -52,7 +60,7 @@ func httpGetToFile(ctx context.Context, url, dst string) error {
 	if err != nil {
 		return err
 	}
-	_, cpErr := io.Copy(f, resp.Body)
+	_, cpErr := io.Copy(io.MultiWriter(f, extra), resp.Body)
 	clErr := f.Close()
 	if cpErr != nil {
 		return cpErr
@@ -81,6 +89,25 @@ func msg(s string) executor.ExecuteOutput {
 	return executor.ExecuteOutput{Message: api.NewPlaintextMessage(s, true)}
 }
 
+// copyFile copies src to dst, overwriting dst if it already exists.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, cpErr := io.Copy(out, in)
+	clErr := out.Close()
+	if cpErr != nil {
+		return cpErr
+	}
+	return clErr
+}
+
 // isExecutable reports whether a file exists and has any execute bit set.
 func isExecutable(path string) bool {
 	st, err := os.Stat(path)
@@ -90,6 +117,113 @@ func isExecutable(path string) bool {
 	return (st.Mode().Perm() & 0o111) != 0
 }
 
+func mergeExecutorConfigs(configs []*executor.Config, out *Config) error {
+	if out.Env == nil {
+		out.Env = map[string]string{}
+	}
+	for _, c := range configs {
+		if c == nil || len(c.RawYAML) == 0 {
+			continue
+		}
+		var t Config
+		if err := yaml.Unmarshal(c.RawYAML, &t); err != nil {
+			return err
+		}
+		if t.DefaultRegion != "" {
+			out.DefaultRegion = t.DefaultRegion
+		}
+		if len(t.PrependArgs) > 0 {
+			out.PrependArgs = t.PrependArgs
+		}
+		if len(t.Allowed) > 0 {
+			out.Allowed = t.Allowed
+		}
+		for k, v := range t.Env {
+			out.Env[k] = v
+		}
+		if t.BundleVerify.SHA256Amd64 != "" {
+			out.BundleVerify.SHA256Amd64 = t.BundleVerify.SHA256Amd64
+		}
+		if t.BundleVerify.SHA256Arm64 != "" {
+			out.BundleVerify.SHA256Arm64 = t.BundleVerify.SHA256Arm64
+		}
+		if t.BundleVerify.SigURLAmd64 != "" {
+			out.BundleVerify.SigURLAmd64 = t.BundleVerify.SigURLAmd64
+		}
+		if t.BundleVerify.SigURLArm64 != "" {
+			out.BundleVerify.SigURLArm64 = t.BundleVerify.SigURLArm64
+		}
+		if t.BundleVerify.PubKey != "" {
+			out.BundleVerify.PubKey = t.BundleVerify.PubKey
+		}
+		if t.AssumeRole.RoleArn != "" {
+			out.AssumeRole = t.AssumeRole
+		}
+		if len(t.Profiles) > 0 {
+			if out.Profiles == nil {
+				out.Profiles = map[string]Profile{}
+			}
+			for name, p := range t.Profiles {
+				out.Profiles[name] = p
+			}
+		}
+		if t.Streaming != (StreamingConfig{}) {
+			out.Streaming = t.Streaming
+		}
+		if t.Artifacts.Bucket != "" {
+			out.Artifacts = t.Artifacts
+		}
+		if len(t.Templates) > 0 {
+			if out.Templates == nil {
+				out.Templates = map[string]Template{}
+			}
+			for id, tpl := range t.Templates {
+				out.Templates[id] = tpl
+			}
+		}
+		if len(t.Policy) > 0 {
+			out.Policy = t.Policy
+		}
+		if len(t.Approvers) > 0 {
+			out.Approvers = t.Approvers
+		}
+		if t.Output != (OutputConfig{}) {
+			out.Output = t.Output
+		}
+		if len(t.Credentials.Providers) > 0 {
+			out.Credentials.Providers = t.Credentials.Providers
+		}
+		if len(t.Credentials.NamedProfiles) > 0 {
+			if out.Credentials.NamedProfiles == nil {
+				out.Credentials.NamedProfiles = map[string]CredentialProfile{}
+			}
+			for name, p := range t.Credentials.NamedProfiles {
+				out.Credentials.NamedProfiles[name] = p
+			}
+		}
+		if t.Credentials.ActiveNamedProfile != "" {
+			out.Credentials.ActiveNamedProfile = t.Credentials.ActiveNamedProfile
+		}
+		if t.InstallMode != "" {
+			out.InstallMode = t.InstallMode
+		}
+		if t.SystemPackage != (SystemPackageConfig{}) {
+			out.SystemPackage = t.SystemPackage
+		}
+	}
+	return nil
+}
+
+func isAllowed(cmd string, allow []string) bool {
+	cmd = strings.TrimSpace(cmd)
+	for _, p := range allow {
+		if strings.HasPrefix(cmd, strings.TrimSpace(p)) {
+			return true
+		}
+	}
+	return false
+}
+
 func normalizeCmd(raw string) string {
 	cmd := strings.TrimSpace(raw)
 	if strings.HasPrefix(strings.ToLower(cmd), pluginName) {
@@ -0,0 +1,192 @@
+// Package extract wraps archive/tar and compress/gzip behind the
+// size-limit and path-safety checks the botkube-awscli bundle/system
+// installers need, so any caller that unpacks an untrusted tar.gz gets the
+// same zip-slip and decompression-bomb protection instead of each call
+// site reimplementing it.
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"archive/tar"
+	"compress/gzip"
+)
+
+// ErrEntryTooLarge is returned when a single tar entry's declared or actual
+// size exceeds Options.MaxEntryBytes.
+var ErrEntryTooLarge = errors.New("extract: tar entry too large")
+
+// ErrArchiveTooLarge is returned when the archive's total decompressed size
+// exceeds Options.MaxArchiveBytes, including when that is only discoverable
+// by the gzip stream itself expanding past the limit (a decompression
+// bomb), not just by summing declared tar entry sizes.
+var ErrArchiveTooLarge = errors.New("extract: archive exceeds total size limit")
+
+// Entry is one regular file TarGz wrote, with Path relative to dst.
+type Entry struct {
+	Path       string
+	Executable bool
+}
+
+// Options bounds a single TarGz call.
+type Options struct {
+	// MaxEntryBytes caps any single tar entry's decompressed size.
+	MaxEntryBytes int64
+	// MaxArchiveBytes caps the archive's cumulative decompressed size.
+	MaxArchiveBytes int64
+}
+
+// TarGz extracts the gzip-compressed tarball at src into dst. It enforces
+// opts' limits with a counting reader around the gzip stream itself (so a
+// small compressed file that decompresses far past MaxArchiveBytes is
+// rejected as it's being read, not after it has been fully inflated into
+// memory or onto disk) and an io.LimitReader per entry. Entry names and
+// symlink/hardlink targets are resolved against dst and rejected if they
+// would escape it; device, fifo, and char-special entries are rejected
+// outright. It returns the regular files it wrote so callers can later
+// confirm the extraction is still intact without re-reading src.
+func TarGz(src, dst string, opts Options) ([]Entry, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(&limitedReader{r: gz, remaining: opts.MaxArchiveBytes + 1})
+	var extracted int64
+	var entries []Entry
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			if errors.Is(err, ErrArchiveTooLarge) {
+				return nil, ErrArchiveTooLarge
+			}
+			return nil, err
+		}
+
+		switch h.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeSymlink, tar.TypeLink:
+		default:
+			return nil, fmt.Errorf("extract: entry %q has unsupported type %q (devices/fifos/char-special are rejected)", h.Name, string(h.Typeflag))
+		}
+
+		target, err := SafeJoin(dst, h.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return nil, err
+			}
+			continue
+		case tar.TypeSymlink, tar.TypeLink:
+			// These bundles never need a real symlink/hardlink on disk;
+			// validate the target can't escape dst and otherwise drop it.
+			if err := validateLinkTarget(dst, h.Name, h.Linkname); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if h.Size < 0 || h.Size > opts.MaxEntryBytes {
+			return nil, fmt.Errorf("%w: %q is %d bytes", ErrEntryTooLarge, h.Name, h.Size)
+		}
+		if extracted+h.Size > opts.MaxArchiveBytes {
+			return nil, ErrArchiveTooLarge
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return nil, err
+		}
+
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		written, cpErr := io.Copy(out, io.LimitReader(tr, opts.MaxEntryBytes+1))
+		clErr := out.Close()
+		if cpErr != nil {
+			return nil, cpErr
+		}
+		if clErr != nil {
+			return nil, clErr
+		}
+		if written > opts.MaxEntryBytes {
+			return nil, fmt.Errorf("%w: %q exceeded its declared size while streaming", ErrEntryTooLarge, h.Name)
+		}
+		extracted += written
+		entries = append(entries, Entry{
+			Path:       strings.TrimPrefix(strings.TrimPrefix(target, dst), string(os.PathSeparator)),
+			Executable: h.Mode&0o111 != 0,
+		})
+	}
+}
+
+// SafeJoin joins base and name, rejecting any result that would escape
+// base via "../" path traversal.
+func SafeJoin(base, name string) (string, error) {
+	path := filepath.Join(base, name)
+	baseAbs, err := filepath.Abs(base)
+	if err != nil {
+		return "", err
+	}
+	pathAbs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if pathAbs != baseAbs && !strings.HasPrefix(pathAbs, baseAbs+string(os.PathSeparator)) {
+		return "", fmt.Errorf("extract: unsafe path: %s", name)
+	}
+	return pathAbs, nil
+}
+
+// validateLinkTarget resolves a tar symlink/hardlink's Linkname the same
+// way the filesystem would when following it from name's location, and
+// rejects it if that resolution would land outside base.
+func validateLinkTarget(base, name, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return fmt.Errorf("extract: entry %q links to absolute path %q", name, linkname)
+	}
+	resolved := filepath.Join(filepath.Dir(name), linkname)
+	if _, err := SafeJoin(base, resolved); err != nil {
+		return fmt.Errorf("extract: entry %q link target %q escapes destination: %w", name, linkname, err)
+	}
+	return nil
+}
+
+// limitedReader wraps r and turns "read past remaining bytes" into
+// ErrArchiveTooLarge instead of silently truncating the stream like
+// io.LimitReader does, so TarGz can tell a real decompression bomb apart
+// from a clean end-of-archive.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, ErrArchiveTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
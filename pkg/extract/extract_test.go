@@ -0,0 +1,170 @@
+package extract
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildTarGz writes entries (headers and, for regular
+// files, their content) into a gzip-compressed tar archive and returns its path under a
+// fresh temp dir.
+func buildTarGz(t *testing.T, entries []tarEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		hdr := e.hdr
+		if hdr.Typeflag == tar.TypeReg && hdr.Size == 0 {
+			hdr.Size = int64(len(e.content))
+		}
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatalf("write header %q: %v", hdr.Name, err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write(e.content); err != nil {
+				t.Fatalf("write content %q: %v", hdr.Name, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar writer: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("close gzip writer: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write tarball: %v", err)
+	}
+	return path
+}
+
+type tarEntry struct {
+	hdr     tar.Header
+	content []byte
+}
+
+func regEntry(name string, content []byte) tarEntry {
+	return tarEntry{hdr: tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}, content: content}
+}
+
+func defaultOpts() Options {
+	return Options{MaxEntryBytes: 1 << 20, MaxArchiveBytes: 10 << 20}
+}
+
+func TestTarGzRejectsPathTraversal(t *testing.T) {
+	src := buildTarGz(t, []tarEntry{regEntry("../evil.txt", []byte("pwn"))})
+	dst := t.TempDir()
+	if _, err := TarGz(src, dst, defaultOpts()); err == nil {
+		t.Fatal("expected an error for a path-traversal entry, got nil")
+	}
+}
+
+func TestTarGzAbsolutePathStaysContained(t *testing.T) {
+	src := buildTarGz(t, []tarEntry{regEntry("/etc/evil.txt", []byte("pwn"))})
+	dst := t.TempDir()
+	entries, err := TarGz(src, dst, defaultOpts())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 extracted entry, got %d", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(dst, entries[0].Path)); err != nil {
+		t.Fatalf("extracted file not found under dst: %v", err)
+	}
+}
+
+func TestTarGzRejectsSymlinkEscape(t *testing.T) {
+	src := buildTarGz(t, []tarEntry{{hdr: tar.Header{
+		Name:     "link",
+		Typeflag: tar.TypeSymlink,
+		Linkname: "/etc/passwd",
+	}}})
+	dst := t.TempDir()
+	if _, err := TarGz(src, dst, defaultOpts()); err == nil {
+		t.Fatal("expected an error for a symlink escaping to /etc/passwd, got nil")
+	}
+}
+
+func TestTarGzRejectsRelativeSymlinkEscape(t *testing.T) {
+	src := buildTarGz(t, []tarEntry{{hdr: tar.Header{
+		Name:     "nested/link",
+		Typeflag: tar.TypeSymlink,
+		Linkname: "../../../etc/passwd",
+	}}})
+	dst := t.TempDir()
+	if _, err := TarGz(src, dst, defaultOpts()); err == nil {
+		t.Fatal("expected an error for a relative symlink escaping dst, got nil")
+	}
+}
+
+func TestTarGzRejectsDeviceEntries(t *testing.T) {
+	src := buildTarGz(t, []tarEntry{{hdr: tar.Header{
+		Name:     "dev/sda",
+		Typeflag: tar.TypeBlock,
+	}}})
+	dst := t.TempDir()
+	if _, err := TarGz(src, dst, defaultOpts()); err == nil {
+		t.Fatal("expected an error for a block device entry, got nil")
+	}
+}
+
+func TestTarGzRejectsOversizedEntry(t *testing.T) {
+	content := bytes.Repeat([]byte{'a'}, 1024)
+	src := buildTarGz(t, []tarEntry{regEntry("big.bin", content)})
+	dst := t.TempDir()
+	_, err := TarGz(src, dst, Options{MaxEntryBytes: 512, MaxArchiveBytes: 10 << 20})
+	if !errors.Is(err, ErrEntryTooLarge) {
+		t.Fatalf("expected ErrEntryTooLarge, got %v", err)
+	}
+}
+
+func TestTarGzRejectsArchiveOverTotalLimit(t *testing.T) {
+	// Each entry individually fits MaxEntryBytes, but the pair together
+	// exceeds MaxArchiveBytes, standing in for a decompression bomb: the
+	// limit is enforced against what actually comes out of the gzip
+	// stream, not just a single entry's declared size.
+	content := bytes.Repeat([]byte{'z'}, 2000)
+	src := buildTarGz(t, []tarEntry{
+		regEntry("a.bin", content),
+		regEntry("b.bin", content),
+	})
+	dst := t.TempDir()
+	_, err := TarGz(src, dst, Options{MaxEntryBytes: 4000, MaxArchiveBytes: 3000})
+	if !errors.Is(err, ErrArchiveTooLarge) {
+		t.Fatalf("expected ErrArchiveTooLarge, got %v", err)
+	}
+}
+
+func TestTarGzExtractsRegularFilesAndReportsExecutableBit(t *testing.T) {
+	src := buildTarGz(t, []tarEntry{
+		{hdr: tar.Header{Name: "bin/aws", Typeflag: tar.TypeReg, Mode: 0o755, Size: 3}, content: []byte("abc")},
+		{hdr: tar.Header{Name: "README.md", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4}, content: []byte("read")},
+	})
+	dst := t.TempDir()
+	entries, err := TarGz(src, dst, defaultOpts())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	byPath := map[string]Entry{}
+	for _, e := range entries {
+		byPath[e.Path] = e
+	}
+	if !byPath["bin/aws"].Executable {
+		t.Error("expected bin/aws to be reported executable")
+	}
+	if byPath["README.md"].Executable {
+		t.Error("expected README.md to be reported non-executable")
+	}
+}